@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/cmd/internal/flagutil"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httputils"
+	"github.com/ironzhang/pearls/uuid"
+)
+
+// 退出码, 调用失败时按错误码的分类返回
+const (
+	exitOK          = 0
+	exitUsage       = 1  // 参数错误
+	exitTransport   = 2  // 网络错误或响应无法解析
+	exitSystem      = 10 // 系统错误, 错误码[-99, -1]
+	exitRequest     = 11 // 请求错误, 错误码[-199, -100]
+	exitCodec       = 12 // 编解码错误, 错误码[-299, -200]
+	exitPermission  = 13 // 权限错误, 错误码[-399, -300]
+	exitApplication = 20 // 业务错误, 其它错误码
+)
+
+const usage = `usage: httprpc-cli [flags] <path> [args]
+
+args为JSON格式的参数, 为"-"时从标准输入读取, 也可以使用-f从文件读取.
+
+退出码:
+  0   成功
+  1   参数错误
+  2   网络错误或响应无法解析
+  10  系统错误, 错误码[-99, -1]
+  11  请求错误, 错误码[-199, -100]
+  12  编解码错误, 错误码[-299, -200]
+  13  权限错误, 错误码[-399, -300]
+  20  业务错误, 其它错误码
+
+flags:
+`
+
+func exitCode(code codes.Code) int {
+	switch {
+	case code == codes.OK:
+		return exitOK
+	case code <= -1 && code >= -99:
+		return exitSystem
+	case code <= -100 && code >= -199:
+		return exitRequest
+	case code <= -200 && code >= -299:
+		return exitCodec
+	case code <= -300 && code >= -399:
+		return exitPermission
+	}
+	return exitApplication
+}
+
+func readArgs(inline, file string) (json.RawMessage, error) {
+	var data []byte
+	var err error
+	switch {
+	case inline != "" && file != "":
+		return nil, errors.New("args and -f are exclusive")
+	case inline == "-" || file == "-":
+		data, err = ioutil.ReadAll(os.Stdin)
+	case file != "":
+		data, err = ioutil.ReadFile(file)
+	default:
+		data = []byte(inline)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) <= 0 {
+		return nil, nil
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("args is not valid JSON")
+	}
+	return data, nil
+}
+
+func main() {
+	var (
+		url      string
+		file     string
+		traceID  string
+		clientID string
+		timeout  time.Duration
+		verbose  bool
+		header   = flagutil.Header(make(http.Header))
+	)
+	flag.StringVar(&url, "url", "http://localhost:8000", "服务地址")
+	flag.StringVar(&file, "f", "", "从文件读取args, 为\"-\"时从标准输入读取")
+	flag.StringVar(&traceID, "trace", "", "X-Trace-Id, 为空时自动生成")
+	flag.StringVar(&clientID, "client", "", "X-Client-Id")
+	flag.DurationVar(&timeout, "timeout", httprpc.HTTPClient.Timeout, "调用超时时间")
+	flag.BoolVar(&verbose, "v", false, "输出请求和响应的原始内容")
+	flag.Var(header, "H", "请求头 <name>: <value>, 可重复指定")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+	args, err := readArgs(flag.Arg(1), file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read args: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	httprpc.HTTPClient.Timeout = timeout
+	if verbose {
+		httprpc.HTTPClient.Transport = httputils.NewVerboseRoundTripper(httputils.NewVerbose(1), os.Stderr, nil)
+	}
+	if traceID == "" {
+		traceID = uuid.New().String()
+	}
+	if clientID != "" {
+		http.Header(header).Set(httputils.X_CLIENT_ID, clientID)
+	}
+	ctx := &httprpc.Context{
+		Context:       context.Background(),
+		TraceID:       traceID,
+		RequestHeader: http.Header(header),
+	}
+
+	var reply json.RawMessage
+	var in interface{}
+	if args != nil {
+		in = args
+	}
+	c := httprpc.NewClient(strings.TrimSuffix(url, "/"), nil)
+	if err = c.Call(ctx, flag.Arg(0), in, &reply); err != nil {
+		os.Exit(printError(traceID, err))
+	}
+
+	var out bytes.Buffer
+	if len(reply) > 0 && json.Indent(&out, reply, "", "  ") == nil {
+		out.WriteByte('\n')
+		out.WriteTo(os.Stdout)
+	} else if len(reply) > 0 {
+		os.Stdout.Write(reply)
+		fmt.Println()
+	}
+}
+
+// printError 输出错误并返回退出码
+func printError(traceID string, err error) int {
+	var e httprpc.ErrorCode
+	if !errors.As(err, &e) {
+		fmt.Fprintf(os.Stderr, "call: %v\ntrace: %s\n", err, traceID)
+		return exitTransport
+	}
+	code := e.Code()
+	fmt.Fprintf(os.Stderr, "code: %d\ndesc: %s\n", code, code)
+	if cause := httprpc.GetErrorCause(err); cause != nil {
+		fmt.Fprintf(os.Stderr, "cause: %v\n", cause)
+	}
+	fmt.Fprintf(os.Stderr, "trace: %s\n", traceID)
+	if stack := httprpc.GetErrorStack(err); len(stack) > 0 {
+		fmt.Fprintf(os.Stderr, "stack:\n%s\n", stack)
+	}
+	return exitCode(code)
+}
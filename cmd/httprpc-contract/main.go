@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/contract"
+)
+
+// load 从文件或contract.Handler的地址读取契约
+func load(src string) (*contract.Contract, error) {
+	var r io.ReadCloser
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		resp, err := http.Get(src)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("get %s: %s", src, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	defer r.Close()
+	return contract.Read(r)
+}
+
+func main() {
+	var breaking bool
+	flag.BoolVar(&breaking, "breaking", false, "只输出不兼容的变化")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), `usage: httprpc-contract [flags] <source>          输出契约
+       httprpc-contract [flags] <old> <new>     对比契约, 有不兼容的变化时退出码为1
+
+source为契约文件或contract.Handler的地址.
+
+flags:
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	old, err := load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load %s: %v\n", flag.Arg(0), err)
+		os.Exit(2)
+	}
+	if flag.NArg() == 1 {
+		old.WriteJSON(os.Stdout)
+		return
+	}
+	cur, err := load(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load %s: %v\n", flag.Arg(1), err)
+		os.Exit(2)
+	}
+	changes := contract.Compare(old, cur)
+	for _, c := range changes {
+		if c.Breaking || !breaking {
+			fmt.Println(c)
+		}
+	}
+	if contract.Breaking(changes) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/cmd/internal/flagutil"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/loadtest"
+)
+
+func main() {
+	var (
+		url      string
+		args     string
+		file     string
+		o        loadtest.Options
+		output   string
+		baseline string
+		header   = flagutil.Header(make(http.Header))
+	)
+	flag.StringVar(&url, "url", "http://localhost:8000", "服务地址")
+	flag.StringVar(&args, "args", "", "参数模板, 支持{{seq}}、{{rand:1-100}}、{{pick:a|b}}、{{uuid}}")
+	flag.StringVar(&file, "f", "", "从文件读取参数模板")
+	flag.IntVar(&o.Concurrency, "c", 10, "并发数, 指定-rate时为同时进行的调用上限")
+	flag.Float64Var(&o.Rate, "rate", 0, "每秒调用次数, 为0时并发调用方连续调用")
+	flag.DurationVar(&o.Duration, "d", 10*time.Second, "压测时长")
+	flag.IntVar(&o.Requests, "n", 0, "总调用次数, 为0时不限制")
+	flag.StringVar(&output, "o", "", "将结果以JSON格式写入文件")
+	flag.StringVar(&baseline, "baseline", "", "与之前写入的JSON结果对比")
+	flag.Var(header, "H", "请求头 <name>: <value>, 可重复指定")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: httprpc-load [flags] <path>\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read args: %v\n", err)
+			os.Exit(2)
+		}
+		args = string(b)
+	}
+	if args != "" {
+		t, err := loadtest.ParseTemplate(args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "parse args: %v\n", err)
+			os.Exit(2)
+		}
+		o.Args = t
+	}
+	var base *loadtest.Result
+	if baseline != "" {
+		f, err := os.Open(baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open baseline: %v\n", err)
+			os.Exit(2)
+		}
+		base, err = loadtest.ReadResult(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read baseline: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	o.URL, o.Path, o.Header = strings.TrimSuffix(url, "/"), flag.Arg(0), http.Header(header)
+
+	// 避免并发调用时频繁新建连接
+	httprpc.HTTPClient.Transport = &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        o.Concurrency,
+		MaxIdleConnsPerHost: o.Concurrency,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	res, err := loadtest.Run(ctx, o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		os.Exit(2)
+	}
+	res.Print(os.Stdout)
+	if base != nil {
+		fmt.Println()
+		res.Compare(os.Stdout, base)
+	}
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "create output: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		if err = res.WriteJSON(f); err != nil {
+			fmt.Fprintf(os.Stderr, "write output: %v\n", err)
+			os.Exit(2)
+		}
+	}
+}
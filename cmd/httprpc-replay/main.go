@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/replay"
+)
+
+type rules []replay.Rule
+
+func (p *rules) String() string {
+	return fmt.Sprint(*p)
+}
+
+func (p *rules) Set(s string) error {
+	r, err := replay.ParseRule(s)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, r)
+	return nil
+}
+
+func main() {
+	var (
+		file    string
+		url     string
+		verbose bool
+		ignore  rules
+	)
+	flag.StringVar(&file, "file", "", "录制文件, 为空则从标准输入读取")
+	flag.StringVar(&url, "url", "http://localhost:8000", "回放目标服务地址")
+	flag.BoolVar(&verbose, "v", false, "输出每一次回放的结果")
+	flag.Var(&ignore, "ignore", "忽略规则 [<path pattern>:]<field path>, 可重复指定")
+	flag.Parse()
+
+	in := os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open: %v\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		in = f
+	}
+	records, err := replay.ReadRecords(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read records: %v\n", err)
+		os.Exit(2)
+	}
+
+	p := replay.Replayer{URL: strings.TrimSuffix(url, "/"), Rules: ignore}
+	failed := 0
+	for i, res := range p.ReplayAll(context.Background(), records) {
+		if res.OK() {
+			if verbose {
+				fmt.Printf("[%d] %s: ok, latency %v -> %v\n", i, res.Record.Path, res.Record.Latency, res.Latency)
+			}
+			continue
+		}
+		failed++
+		if res.Err != nil {
+			fmt.Printf("[%d] %s: error: %v\n", i, res.Record.Path, res.Err)
+			continue
+		}
+		fmt.Printf("[%d] %s: %d diffs\n", i, res.Record.Path, len(res.Diffs))
+		for _, d := range res.Diffs {
+			fmt.Printf("\t%s\n", d)
+		}
+	}
+	fmt.Printf("total: %d, failed: %d\n", len(records), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,24 @@
+// Package flagutil 命令行工具共用的flag类型
+package flagutil
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Header 可重复指定的请求头, 格式为<name>: <value>
+type Header http.Header
+
+func (p Header) String() string {
+	return fmt.Sprint(http.Header(p))
+}
+
+func (p Header) Set(s string) error {
+	i := strings.Index(s, ":")
+	if i <= 0 {
+		return fmt.Errorf("invalid header %q, must be <name>: <value>", s)
+	}
+	http.Header(p).Add(strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]))
+	return nil
+}
@@ -0,0 +1,166 @@
+package httprpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/zaplog"
+)
+
+// Sampling 路径的访问日志采样率, Path使用MatchPath匹配, Rate取值[0, 1].
+type Sampling struct {
+	Path string
+	Rate float64
+}
+
+// AccessLogOptions 访问日志选项.
+//
+// Sampling按顺序匹配, 未匹配的路径全部记录; 出错和超过SlowThreshold的调用不受采样限制.
+// BodyLimit大于0时记录args和reply, 超过BodyLimit字节的部分被截断.
+type AccessLogOptions struct {
+	Sampling      []Sampling
+	SlowThreshold time.Duration
+	BodyLimit     int
+}
+
+// AccessLog 访问日志中间件, 每次调用输出一条日志.
+type AccessLog struct {
+	logger  *zaplog.Logger
+	options AccessLogOptions
+}
+
+// NewAccessLog 创建访问日志中间件, l为nil时使用Logger().
+func NewAccessLog(l *zaplog.Logger, o AccessLogOptions) *AccessLog {
+	return &AccessLog{logger: l, options: o}
+}
+
+func (a *AccessLog) sampleRate(path string) float64 {
+	for _, s := range a.options.Sampling {
+		if MatchPath(s.Path, path) {
+			return s.Rate
+		}
+	}
+	return 1
+}
+
+func (a *AccessLog) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, next NextMiddleware) error {
+	start := time.Now()
+	body := &countReader{ReadCloser: r.Body, limit: a.options.BodyLimit}
+	if r.Body != nil {
+		r.Body = body
+	}
+	aw := &accessWriter{ResponseWriter: w, status: http.StatusOK, limit: a.options.BodyLimit}
+	err := next(ctx, aw, r)
+	latency := time.Since(start)
+
+	code, status, size, reply := codes.OK, aw.status, aw.size, aw.buf
+	if err != nil {
+		var er errReply
+		code, er = newErrReply(err, false)
+		status = code.Status()
+		reply, _ = json.Marshal(er)
+		size = len(reply)
+		reply = truncate(reply, a.options.BodyLimit)
+	}
+
+	slow := a.options.SlowThreshold > 0 && latency >= a.options.SlowThreshold
+	if err == nil && !slow {
+		if rate := a.sampleRate(normalizePath(r.URL.Path)); rate < 1 && rand.Float64() >= rate {
+			return err
+		}
+	}
+
+	l := a.logger
+	if l == nil {
+		l = Logger()
+	}
+	kvs := []interface{}{
+		"clientId", r.Header.Get(xClientID),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"code", int(code),
+		"status", status,
+		"latency", latency,
+		"requestSize", body.n,
+		"responseSize", size,
+	}
+	if a.options.BodyLimit > 0 {
+		kvs = append(kvs, "args", string(body.buf), "reply", string(reply))
+	}
+	if status >= http.StatusInternalServerError {
+		if stack := GetErrorStack(err); len(stack) > 0 {
+			kvs = append(kvs, "stack", string(stack))
+		}
+	}
+	log := l.WithContext(ctx)
+	switch {
+	case slow:
+		log.Warnw("slow call", kvs...)
+	case status >= http.StatusInternalServerError:
+		log.Warnw("access", kvs...)
+	default:
+		log.Infow("access", kvs...)
+	}
+	return err
+}
+
+func truncate(b []byte, limit int) []byte {
+	if limit <= 0 {
+		return nil
+	}
+	if len(b) > limit {
+		return b[:limit]
+	}
+	return b
+}
+
+// countReader 统计请求体大小, 并保留前limit个字节.
+type countReader struct {
+	io.ReadCloser
+	limit int
+	n     int
+	buf   []byte
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += n
+	if rest := r.limit - len(r.buf); rest > 0 {
+		r.buf = append(r.buf, truncate(p[:n], rest)...)
+	}
+	return n, err
+}
+
+// accessWriter 统计响应状态和大小, 并保留前limit个字节.
+type accessWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+	limit  int
+	buf    []byte
+}
+
+func (w *accessWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	if rest := w.limit - len(w.buf); rest > 0 {
+		w.buf = append(w.buf, truncate(b[:n], rest)...)
+	}
+	return n, err
+}
@@ -0,0 +1,96 @@
+package httprpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/zaplog"
+)
+
+type Sleeper struct{}
+
+func (Sleeper) Sleep(ctx context.Context, args *Args, reply *Reply) error {
+	time.Sleep(time.Duration(args.A) * time.Millisecond)
+	reply.C = args.A
+	return nil
+}
+
+func TestAccessLog(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	var a Arith
+	s := NewServer(nil)
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/sleeper", Sleeper{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	s.AddMiddleware(NewAccessLog(zaplog.NewLogger(zap.New(core)), AccessLogOptions{
+		Sampling:      []Sampling{{Path: "/sleeper/**", Rate: 0}},
+		SlowThreshold: 20 * time.Millisecond,
+		BodyLimit:     8,
+	}))
+
+	tests := []struct {
+		path    string
+		body    string
+		logged  bool
+		level   zapcore.Level
+		code    int64
+		args    string
+		reply   string
+		reqSize int64
+	}{
+		{path: "/arith/Add", body: `{"A":1,"B":2}`, logged: true, level: zapcore.InfoLevel, args: `{"A":1,"`, reply: `{"C":3}` + "\n", reqSize: 13},
+		{path: "/arith/Div", body: `{"A":1,"B":0}`, logged: true, level: zapcore.WarnLevel, code: -1, args: `{"A":1,"`, reply: `{"Code":`, reqSize: 13},
+		{path: "/sleeper/Sleep", body: `{"A":1}`, logged: false},
+		{path: "/sleeper/Sleep", body: `{"A":30}`, logged: true, level: zapcore.WarnLevel, args: `{"A":30}`, reply: `{"C":30}`, reqSize: 8},
+	}
+	for i, tt := range tests {
+		logs.TakeAll()
+		serveTestHTTP(s, "POST", tt.path, []byte(tt.body))
+		entries := logs.TakeAll()
+		if !tt.logged {
+			if len(entries) != 0 {
+				t.Errorf("case%d: got %d entries, want 0", i, len(entries))
+			}
+			continue
+		}
+		if len(entries) != 1 {
+			t.Errorf("case%d: got %d entries, want 1", i, len(entries))
+			continue
+		}
+		e := entries[0]
+		fields := e.ContextMap()
+		if e.Level != tt.level {
+			t.Errorf("case%d: level: got %v, want %v", i, e.Level, tt.level)
+		}
+		if fields["path"] != tt.path || fields["code"] != tt.code || fields["requestSize"] != tt.reqSize {
+			t.Errorf("case%d: fields: %v", i, fields)
+		}
+		if fields["args"] != tt.args || fields["reply"] != tt.reply {
+			t.Errorf("case%d: args: %q, reply: %q", i, fields["args"], fields["reply"])
+		}
+		if _, ok := fields["TraceID"]; !ok {
+			t.Errorf("case%d: no TraceID", i)
+		}
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(nil)
+	l := zaplog.NewLogger(zap.NewNop())
+	SetLogger(l)
+	if Logger() != l {
+		t.Errorf("Logger: not the logger set")
+	}
+	SetLogger(nil)
+	if Logger() != zaplog.Std {
+		t.Errorf("Logger: not zaplog.Std")
+	}
+}
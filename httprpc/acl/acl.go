@@ -0,0 +1,193 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Identity 调用者身份
+type Identity struct {
+	ClientID string
+	Roles    []string
+}
+
+// IdentityFunc 从请求中获取已认证的调用者身份
+type IdentityFunc func(ctx context.Context, r *http.Request) Identity
+
+// HeaderIdentity 使用X-Client-Id请求头作为调用者的client id, 适用于认证已在前置网关完成的场景.
+func HeaderIdentity(ctx context.Context, r *http.Request) Identity {
+	return Identity{ClientID: r.Header.Get("X-Client-Id")}
+}
+
+// Rule 访问控制规则.
+//
+// Paths使用httprpc.MatchPath匹配类和方法路径; Clients和Roles为空时匹配任意调用者,
+// 否则调用者的client id或任一角色命中即匹配, "*"匹配任意值.
+type Rule struct {
+	Effect  string
+	Paths   []string
+	Clients []string `json:",omitempty" toml:",omitempty"`
+	Roles   []string `json:",omitempty" toml:",omitempty"`
+}
+
+func (r *Rule) matchPath(p string) bool {
+	for _, pattern := range r.Paths {
+		if httprpc.MatchPath(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchIdentity(id Identity) bool {
+	if len(r.Clients) <= 0 && len(r.Roles) <= 0 {
+		return true
+	}
+	for _, c := range r.Clients {
+		if c == "*" || (c == id.ClientID && c != "") {
+			return true
+		}
+	}
+	for _, role := range r.Roles {
+		for _, v := range id.Roles {
+			if role == "*" || role == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Policy 访问控制策略, deny规则优先于allow规则, 没有规则匹配时拒绝访问.
+type Policy struct {
+	Rules []Rule
+}
+
+func (p *Policy) Validate() error {
+	for i, r := range p.Rules {
+		if r.Effect != Allow && r.Effect != Deny {
+			return fmt.Errorf("rule %d: invalid effect %q", i, r.Effect)
+		}
+		if len(r.Paths) <= 0 {
+			return fmt.Errorf("rule %d: no paths", i)
+		}
+		for _, pattern := range r.Paths {
+			if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+				return fmt.Errorf("rule %d: invalid path pattern %q: %v", i, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check 检查id是否可以访问path, 返回是否允许及决定结果的规则下标, 默认拒绝时下标为-1.
+func (p *Policy) Check(id Identity, path string) (bool, int) {
+	allow := -1
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if !r.matchPath(path) || !r.matchIdentity(id) {
+			continue
+		}
+		if r.Effect == Deny {
+			return false, i
+		}
+		if allow < 0 {
+			allow = i
+		}
+	}
+	return allow >= 0, allow
+}
+
+// Loader 配置加载器, 可使用pluginapp/configurator下的jsonc.Configurator或tomlc.Configurator.
+type Loader interface {
+	LoadFromFile(filename string, configs map[string]interface{}) error
+}
+
+// LoadPolicy 从配置文件的"acl"节点中加载策略
+func LoadPolicy(l Loader, filename string) (*Policy, error) {
+	var p Policy
+	if err := l.LoadFromFile(filename, map[string]interface{}{"acl": &p}); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Authorizer 访问控制中间件
+type Authorizer struct {
+	policy   atomic.Value
+	identify IdentityFunc
+}
+
+// NewAuthorizer 创建访问控制中间件, identify为nil时使用HeaderIdentity.
+func NewAuthorizer(p *Policy, identify IdentityFunc) (*Authorizer, error) {
+	if identify == nil {
+		identify = HeaderIdentity
+	}
+	a := &Authorizer{identify: identify}
+	if err := a.SetPolicy(p); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetPolicy 替换访问控制策略, 可在运行时调用.
+func (a *Authorizer) SetPolicy(p *Policy) error {
+	if p == nil {
+		p = &Policy{}
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	a.policy.Store(p)
+	return nil
+}
+
+func (a *Authorizer) Policy() *Policy {
+	return a.policy.Load().(*Policy)
+}
+
+func (a *Authorizer) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, next httprpc.NextMiddleware) error {
+	id := a.identify(ctx, r)
+	ok, rule := a.check(ctx, id, r.URL.Path)
+	if !ok {
+		httprpc.Logger().WithContext(ctx).WithFunction("acl").Warnw("permission denied", "path", r.URL.Path,
+			"clientId", id.ClientID, "roles", id.Roles, "rule", rule)
+		return httprpc.Errorf(codes.PermissionDenied, "%s is not allowed to call %s", id.ClientID, r.URL.Path)
+	}
+	return next(ctx, w, r)
+}
+
+// check 别名路径同时检查解析后的方法路径, 两个路径都不能被拒绝, 任一路径被允许即可.
+func (a *Authorizer) check(ctx context.Context, id Identity, path string) (bool, int) {
+	p := a.Policy()
+	ok, rule := p.Check(id, path)
+	rctx, isCtx := ctx.(*httprpc.Context)
+	if !isCtx || rctx.MethodPath == "" || rctx.MethodPath == path {
+		return ok, rule
+	}
+	mok, mrule := p.Check(id, rctx.MethodPath)
+	switch {
+	case !ok && rule >= 0:
+		return false, rule
+	case !mok && mrule >= 0:
+		return false, mrule
+	case ok:
+		return true, rule
+	}
+	return mok, mrule
+}
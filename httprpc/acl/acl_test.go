@@ -0,0 +1,179 @@
+package acl
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/pluginapp/configurator/jsonc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/pluginapp/configurator/tomlc"
+)
+
+var testPolicy = Policy{
+	Rules: []Rule{
+		{Effect: Allow, Paths: []string{"/device/**"}, Roles: []string{"admin"}},
+		{Effect: Allow, Paths: []string{"/device/v1/Get", "/device/v1/List"}, Clients: []string{"*"}},
+		{Effect: Deny, Paths: []string{"/device/v1/Reboot"}, Clients: []string{"c2"}},
+	},
+}
+
+func TestPolicyCheck(t *testing.T) {
+	tests := []struct {
+		id    Identity
+		path  string
+		allow bool
+		rule  int
+	}{
+		{id: Identity{ClientID: "c1", Roles: []string{"admin"}}, path: "/device/v1/Reboot", allow: true, rule: 0},
+		{id: Identity{ClientID: "c2", Roles: []string{"admin"}}, path: "/device/v1/Reboot", allow: false, rule: 2},
+		{id: Identity{ClientID: "c3"}, path: "/device/v1/Get", allow: true, rule: 1},
+		{id: Identity{ClientID: "c3"}, path: "/device/v1/Reboot", allow: false, rule: -1},
+		{id: Identity{ClientID: "c3", Roles: []string{"admin"}}, path: "/user/v1/Get", allow: false, rule: -1},
+	}
+	for i, tt := range tests {
+		allow, rule := testPolicy.Check(tt.id, tt.path)
+		if allow != tt.allow || rule != tt.rule {
+			t.Errorf("case%d: Check(%v, %s): got (%v, %d), want (%v, %d)", i, tt.id, tt.path, allow, rule, tt.allow, tt.rule)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []Policy{
+		{Rules: []Rule{{Effect: "permit", Paths: []string{"/a"}}}},
+		{Rules: []Rule{{Effect: Allow}}},
+		{Rules: []Rule{{Effect: Deny, Paths: []string{"/a/["}}}},
+	}
+	for i, p := range tests {
+		if err := p.Validate(); err == nil {
+			t.Errorf("case%d: Validate return error is nil", i)
+		} else {
+			t.Logf("case%d: Validate: %v", i, err)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatalf("temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		loader Loader
+		file   string
+		data   string
+	}{
+		{
+			loader: jsonc.Configurator,
+			file:   "acl.json",
+			data:   `{"acl": {"Rules": [{"Effect": "allow", "Paths": ["/device/**"], "Roles": ["admin"]}]}}`,
+		},
+		{
+			loader: tomlc.Configurator,
+			file:   "acl.toml",
+			data:   "[acl]\n[[acl.Rules]]\nEffect = \"allow\"\nPaths = [\"/device/**\"]\nRoles = [\"admin\"]\n",
+		},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(dir, tt.file)
+		if err = ioutil.WriteFile(filename, []byte(tt.data), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		p, err := LoadPolicy(tt.loader, filename)
+		if err != nil {
+			t.Fatalf("%s: LoadPolicy: %v", tt.file, err)
+		}
+		if ok, _ := p.Check(Identity{Roles: []string{"admin"}}, "/device/Get"); !ok {
+			t.Errorf("%s: policy: %+v", tt.file, p)
+		}
+	}
+}
+
+type Device int
+
+func (d *Device) Get(ctx context.Context, args string, reply *string) error {
+	*reply = args
+	return nil
+}
+
+func (d *Device) Reboot(ctx context.Context, args string, reply *string) error {
+	return nil
+}
+
+func TestAuthorizer(t *testing.T) {
+	var d Device
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/device/v1", &d); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	roles := func(ctx context.Context, r *http.Request) Identity {
+		id := HeaderIdentity(ctx, r)
+		if v := r.Header.Get("X-Test-Roles"); v != "" {
+			id.Roles = strings.Split(v, ",")
+		}
+		return id
+	}
+	a, err := NewAuthorizer(&testPolicy, roles)
+	if err != nil {
+		t.Fatalf("NewAuthorizer: %v", err)
+	}
+	s.AddMiddleware(a)
+
+	tests := []struct {
+		path   string
+		client string
+		roles  string
+		status int
+	}{
+		{path: "/device/v1/Get", client: "c1", status: http.StatusOK},
+		{path: "/device/v1/Reboot", client: "c1", status: http.StatusForbidden},
+		{path: "/device/v1/Reboot", client: "c1", roles: "admin", status: http.StatusOK},
+		{path: "/device/v1/Reboot", client: "c2", roles: "admin", status: http.StatusForbidden},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest("POST", tt.path, strings.NewReader(`"a"`))
+		r.Header.Set("X-Client-Id", tt.client)
+		r.Header.Set("X-Test-Roles", tt.roles)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, tt.status; got != want {
+			t.Errorf("case%d: status: got %v, want %v, body: %s", i, got, want, w.Body)
+		}
+	}
+
+	// 别名不能绕过针对实际路径的规则
+	if err = s.Alias("/device/v0", "/device/v1"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+	for i, tt := range []struct {
+		path   string
+		client string
+		roles  string
+		status int
+	}{
+		{path: "/device/v0/Get", client: "c1", status: http.StatusOK},
+		{path: "/device/v0/Reboot", client: "c1", status: http.StatusForbidden},
+		{path: "/device/v0/Reboot", client: "c2", roles: "admin", status: http.StatusForbidden},
+	} {
+		r := httptest.NewRequest("POST", tt.path, strings.NewReader(`"a"`))
+		r.Header.Set("X-Client-Id", tt.client)
+		r.Header.Set("X-Test-Roles", tt.roles)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, tt.status; got != want {
+			t.Errorf("alias case%d: status: got %v, want %v, body: %s", i, got, want, w.Body)
+		}
+	}
+
+	if err = a.SetPolicy(&Policy{Rules: []Rule{{Effect: "x"}}}); err == nil {
+		t.Errorf("SetPolicy return error is nil")
+	}
+}
@@ -0,0 +1,286 @@
+package httprpc
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
+)
+
+const xCache = "X-Cache"
+
+// CacheRule 方法的缓存配置.
+//
+// TTL小于等于0时缓存项不过期, 只会被LRU淘汰或显式失效;
+// VaryHeader不为空时该请求头的值也作为缓存键的一部分, 例如租户头.
+// 默认按调用方缓存, 调用方由X-Client-ID、Authorization和Cookie请求头区分;
+// Shared为true时所有调用方共用缓存, 只用于响应与调用方无关的方法.
+type CacheRule struct {
+	TTL        time.Duration
+	VaryHeader string
+	Shared     bool
+}
+
+// cacheCallerHeaders 区分调用方的请求头
+var cacheCallerHeaders = []string{xClientID, "Authorization", "Cookie"}
+
+// CacheStats 缓存统计
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+	Bytes     int64
+}
+
+type cacheEntry struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.body))
+}
+
+// Cache 响应缓存中间件, 只缓存通过SetMethod配置的方法的成功响应, 上传文件和流式读写的方法不缓存.
+//
+// 缓存键为"路径|调用方|VaryHeader的值|规范化编码后的参数", Invalidate按该键的前缀失效,
+// 例如Invalidate("/catalog/")失效/catalog下所有方法的缓存.
+type Cache struct {
+	maxEntries int
+	maxBytes   int64
+
+	mu      sync.Mutex
+	rules   map[string]CacheRule
+	ll      *list.List
+	entries map[string]*list.Element
+	bytes   int64
+	stats   CacheStats
+}
+
+// NewCache 创建响应缓存, maxEntries和maxBytes小于等于0时不限制.
+func NewCache(maxEntries int, maxBytes int64) *Cache {
+	return &Cache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		rules:      make(map[string]CacheRule),
+		ll:         list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// SetMethod 为方法路径开启缓存
+func (c *Cache) SetMethod(path string, rule CacheRule) {
+	c.mu.Lock()
+	c.rules[normalizePath(path)] = rule
+	c.mu.Unlock()
+}
+
+// Invalidate 删除键以prefix开头的缓存项, 返回删除的数量.
+func (c *Cache) Invalidate(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for key, e := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(e)
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.Entries = c.ll.Len()
+	s.Bytes = c.bytes
+	return s
+}
+
+func (c *Cache) rule(path string) (CacheRule, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rule, ok := c.rules[path]
+	return rule, ok
+}
+
+func (c *Cache) get(key string, now time.Time) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		ent := e.Value.(*cacheEntry)
+		if ent.expires.IsZero() || now.Before(ent.expires) {
+			c.ll.MoveToFront(e)
+			c.stats.Hits++
+			return ent
+		}
+		c.remove(e)
+	}
+	c.stats.Misses++
+	return nil
+}
+
+func (c *Cache) add(ent *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxBytes > 0 && ent.size() > c.maxBytes {
+		return
+	}
+	if e, ok := c.entries[ent.key]; ok {
+		c.remove(e)
+	}
+	c.entries[ent.key] = c.ll.PushFront(ent)
+	c.bytes += ent.size()
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.ll.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	ent := c.ll.Remove(e).(*cacheEntry)
+	delete(c.entries, ent.key)
+	c.bytes -= ent.size()
+}
+
+func (c *Cache) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, next NextMiddleware) error {
+	path := normalizePath(r.URL.Path)
+	rule, ok := c.rule(path)
+	if !ok {
+		return next(ctx, w, r)
+	}
+	if rctx, ok := ctx.(*Context); ok && !recordable(rctx.method, r) {
+		return next(ctx, w, r)
+	}
+
+	var args []byte
+	if r.Method == http.MethodGet {
+		args = []byte(r.URL.Query().Encode())
+	} else {
+		// 请求体已由Server按大小上限包装
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return bodyError(err)
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		args = canonicalArgs(body)
+	}
+	var vary string
+	if rule.VaryHeader != "" {
+		vary = r.Header.Get(rule.VaryHeader)
+	}
+	var caller string
+	if !rule.Shared {
+		caller = callerKey(r.Header)
+	}
+	key := path + "|" + caller + "|" + vary + "|" + string(args)
+
+	now := time.Now()
+	if ent := c.get(key, now); ent != nil {
+		setSpanCache(ctx, "hit")
+		c.write(ctx, w, r, ent)
+		return nil
+	}
+	setSpanCache(ctx, "miss")
+
+	w.Header().Set(xCache, "MISS")
+	rc := newResponseCapture(w)
+	if err := next(ctx, rc, r); err != nil {
+		return err
+	}
+	if rc.status != http.StatusOK {
+		return nil
+	}
+	ent := &cacheEntry{
+		key:    key,
+		status: rc.status,
+		header: cachedHeader(ctx, w.Header()),
+		body:   rc.body.Bytes(),
+	}
+	if rule.TTL > 0 {
+		ent.expires = now.Add(rule.TTL)
+	}
+	c.add(ent)
+	return nil
+}
+
+func (c *Cache) write(ctx context.Context, w http.ResponseWriter, r *http.Request, ent *cacheEntry) {
+	h := w.Header()
+	for k, v := range ent.header {
+		h[k] = v
+	}
+	if rctx, ok := ctx.(*Context); ok {
+		setHeaderTraceID(h, rctx.TraceID)
+	}
+	h.Set(xCache, "HIT")
+	if etag := h.Get("ETag"); etag != "" && r.Method == http.MethodGet && notModified(r.Header, h, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(ent.status)
+	w.Write(ent.body)
+}
+
+// cachedHeader 只缓存方法产生的响应头, CORS、trace id等每个请求不同的头不缓存
+func cachedHeader(ctx context.Context, h http.Header) http.Header {
+	keys := []string{"Content-Type", "ETag", headerDeprecation, headerSunset, headerLink}
+	if rctx, ok := ctx.(*Context); ok {
+		for key := range rctx.ResponseHeader {
+			keys = append(keys, key)
+		}
+	}
+	ch := make(http.Header)
+	for _, key := range keys {
+		key = http.CanonicalHeaderKey(key)
+		if values, ok := h[key]; ok {
+			ch[key] = append([]string(nil), values...)
+		}
+	}
+	return ch
+}
+
+// callerKey 返回区分调用方的请求头的摘要, 缓存键中不保存凭据原文.
+func callerKey(h http.Header) string {
+	var b strings.Builder
+	for _, key := range cacheCallerHeaders {
+		b.WriteString(strconv.Quote(h.Get(key)))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// canonicalArgs 将JSON参数重新编码, 使字段顺序和空白不同的相同参数得到相同的键.
+func canonicalArgs(body []byte) []byte {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return bytes.TrimSpace(body)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return bytes.TrimSpace(body)
+	}
+	return b
+}
+
+func setSpanCache(ctx context.Context, result string) {
+	if span := tracing.SpanFromContext(ctx); span != nil {
+		span.SetAttribute("rpc.cache", result)
+	}
+}
@@ -0,0 +1,235 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Counter struct {
+	calls int
+}
+
+func (c *Counter) Add(ctx context.Context, args *Args, reply *Reply) error {
+	c.calls++
+	reply.C = args.A + args.B
+	return nil
+}
+
+func (c *Counter) Fail(ctx context.Context, args *Args, reply *Reply) error {
+	c.calls++
+	return Errorf(-1, "fail")
+}
+
+func TestCache(t *testing.T) {
+	var c Counter
+	s := NewServer(nil)
+	if err := s.Register("/counter", &c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	cache := NewCache(2, 0)
+	cache.SetMethod("/counter/Add", CacheRule{VaryHeader: "X-Tenant"})
+	cache.SetMethod("/counter/Fail", CacheRule{})
+	s.AddMiddleware(cache)
+
+	call := func(path, body, tenant string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if tenant != "" {
+			r.Header.Set("X-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		path   string
+		body   string
+		tenant string
+		cache  string
+		calls  int
+	}{
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, cache: "MISS", calls: 1},
+		{path: "/counter/Add", body: `{ "B":2, "A":1 }`, cache: "HIT", calls: 1},
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, tenant: "t1", cache: "MISS", calls: 2},
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, tenant: "t1", cache: "HIT", calls: 2},
+		{path: "/counter/Add", body: `{"A":2,"B":2}`, cache: "MISS", calls: 3},
+		// LRU淘汰了无租户的{"A":1,"B":2}
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, cache: "MISS", calls: 4},
+		{path: "/counter/Fail", body: `{}`, cache: "MISS", calls: 5},
+		{path: "/counter/Fail", body: `{}`, cache: "MISS", calls: 6},
+	}
+	for i, tt := range tests {
+		w := call(tt.path, tt.body, tt.tenant)
+		if got, want := w.Header().Get(xCache), tt.cache; got != want {
+			t.Errorf("case%d: X-Cache: got %v, want %v", i, got, want)
+		}
+		if got, want := c.calls, tt.calls; got != want {
+			t.Errorf("case%d: calls: got %v, want %v", i, got, want)
+		}
+		if w.Code == http.StatusOK && strings.TrimSpace(w.Body.String()) == "" {
+			t.Errorf("case%d: empty body", i)
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 6 || stats.Evictions != 2 || stats.Entries != 2 {
+		t.Errorf("stats: %+v", stats)
+	}
+
+	if n := cache.Invalidate("/counter/Add|t1|"); n != 0 {
+		t.Errorf("invalidate t1: got %d, want 0", n)
+	}
+	if n := cache.Invalidate("/counter/"); n != 2 {
+		t.Errorf("invalidate /counter/: got %d, want 2", n)
+	}
+	if w := call("/counter/Add", `{"A":1,"B":2}`, ""); w.Header().Get(xCache) != "MISS" {
+		t.Errorf("after invalidate: X-Cache: %s", w.Header().Get(xCache))
+	}
+}
+
+func TestCacheCaller(t *testing.T) {
+	var c Counter
+	s := NewServer(nil)
+	if err := s.Register("/counter", &c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/firmware", Firmware{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	cache := NewCache(0, 0)
+	cache.SetMethod("/counter/Add", CacheRule{})
+	cache.SetMethod("/counter/Fail", CacheRule{Shared: true})
+	cache.SetMethod("/firmware/Download", CacheRule{Shared: true})
+	s.AddMiddleware(cache)
+
+	call := func(path, body, client string) string {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set(xClientID, client)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w.Header().Get(xCache)
+	}
+
+	tests := []struct {
+		path   string
+		body   string
+		client string
+		cache  string
+	}{
+		// 默认按调用方缓存
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, client: "c1", cache: "MISS"},
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, client: "c2", cache: "MISS"},
+		{path: "/counter/Add", body: `{"A":1,"B":2}`, client: "c1", cache: "HIT"},
+		// 流式方法不缓存
+		{path: "/firmware/Download", body: `"v1"`, client: "c1", cache: ""},
+		{path: "/firmware/Download", body: `"v1"`, client: "c1", cache: ""},
+	}
+	for i, tt := range tests {
+		if got := call(tt.path, tt.body, tt.client); got != tt.cache {
+			t.Errorf("case%d: X-Cache: got %q, want %q", i, got, tt.cache)
+		}
+	}
+
+	// Shared时调用方共用缓存
+	cache.SetMethod("/counter/Add", CacheRule{Shared: true})
+	call("/counter/Add", `{"A":2,"B":2}`, "c1")
+	if got := call("/counter/Add", `{"A":2,"B":2}`, "c2"); got != "HIT" {
+		t.Errorf("shared: X-Cache: got %q, want HIT", got)
+	}
+}
+
+func TestResponseWriterFlush(t *testing.T) {
+	for _, w := range []http.ResponseWriter{
+		newResponseCapture(httptest.NewRecorder()),
+		&accessWriter{ResponseWriter: httptest.NewRecorder()},
+	} {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatalf("%T does not implement http.Flusher", w)
+		}
+		f.Flush()
+		var rec *httptest.ResponseRecorder
+		switch w := w.(type) {
+		case *responseCapture:
+			rec = w.ResponseWriter.(*httptest.ResponseRecorder)
+		case *accessWriter:
+			rec = w.ResponseWriter.(*httptest.ResponseRecorder)
+		}
+		if !rec.Flushed {
+			t.Errorf("%T: Flush not forwarded", w)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	cache := NewCache(0, 0)
+	now := time.Now()
+	cache.add(&cacheEntry{key: "a", status: http.StatusOK, expires: now.Add(time.Second)})
+	if cache.get("a", now) == nil {
+		t.Errorf("get before expires: nil")
+	}
+	if cache.get("a", now.Add(2*time.Second)) != nil {
+		t.Errorf("get after expires: not nil")
+	}
+	if n := cache.Stats().Entries; n != 0 {
+		t.Errorf("entries: got %d, want 0", n)
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	cache := NewCache(0, 10)
+	cache.add(&cacheEntry{key: "a", body: []byte("0123")})
+	cache.add(&cacheEntry{key: "b", body: []byte("0123")})
+	cache.add(&cacheEntry{key: "c", body: []byte("01234")})
+	cache.add(&cacheEntry{key: "d", body: []byte("0123456789")})
+	if s := cache.Stats(); s.Entries != 1 || s.Bytes != 6 || s.Evictions != 2 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestCacheHeader(t *testing.T) {
+	var c Counter
+	s := NewServer(nil)
+	if err := s.Register("/counter", &c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	s.SetCORS(NewCORS("https://a.example.com", "https://b.example.com"))
+	if err := s.SetBodyLimit(16, "/counter/Add"); err != nil {
+		t.Fatalf("SetBodyLimit: %v", err)
+	}
+	cache := NewCache(0, 0)
+	cache.SetMethod("/counter/Add", CacheRule{})
+	s.AddMiddleware(cache)
+
+	call := func(origin, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/counter/Add", strings.NewReader(body))
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+	call("https://a.example.com", `{"A":1,"B":2}`)
+	w := call("https://b.example.com", `{"A":1,"B":2}`)
+	if got, want := w.Header().Get(xCache), "HIT"; got != want {
+		t.Fatalf("X-Cache: got %v, want %v", got, want)
+	}
+	if got, want := w.Header().Get("Access-Control-Allow-Origin"), "https://b.example.com"; got != want {
+		t.Errorf("Access-Control-Allow-Origin: got %v, want %v", got, want)
+	}
+	if got, want := w.Header().Get("Content-Type"), DefaultCodec.ContentType(); got != want {
+		t.Errorf("Content-Type: got %v, want %v", got, want)
+	}
+
+	// 中间件读取请求体时受SetBodyLimit限制
+	r := httptest.NewRequest("POST", "/counter/Add", strings.NewReader(`{"A":1,"B":2,"C":3}`))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if got, want := w.Code, http.StatusRequestEntityTooLarge; got != want {
+		t.Errorf("status: got %v, want %v", got, want)
+	}
+}
@@ -3,10 +3,10 @@ package httprpc
 import (
 	"bytes"
 	"context"
+	"io/ioutil"
 	"net/http"
+	"sync"
 	"time"
-
-	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
 )
 
 var HTTPClient = http.Client{
@@ -14,8 +14,10 @@ var HTTPClient = http.Client{
 }
 
 type Client struct {
-	url   string
-	codec Codec
+	url        string
+	codec      Codec
+	deprecated sync.Map
+	hedging    *hedging
 }
 
 func NewClient(url string, codec Codec) *Client {
@@ -33,32 +35,61 @@ func (c *Client) Call(ctx context.Context, path string, args, reply interface{})
 		}
 	}
 
-	url := c.url + normalizePath(path)
-	req, err := http.NewRequest("POST", url, &buf)
+	path = normalizePath(path)
+	var data []byte
+	if h := c.hedging; h != nil && h.match(path) {
+		data, err = c.hedgedCall(ctx, h, path, buf.Bytes())
+	} else {
+		data, _, err = c.roundTrip(ctx, nil, c.url, path, buf.Bytes(), false)
+	}
 	if err != nil {
 		return err
 	}
+	if reply != nil {
+		if err = c.codec.Decode(bytes.NewReader(data), reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundTrip 发送一次请求, 返回响应体. reqCtx不为nil时用于取消请求.
+// replied表示得到了服务端的完整响应, 包括服务端返回的错误.
+func (c *Client) roundTrip(ctx, reqCtx context.Context, base, path string, body []byte, hedge bool) (data []byte, replied bool, err error) {
+	req, err := http.NewRequest("POST", base+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	if reqCtx != nil {
+		req = req.WithContext(reqCtx)
+	}
 	c.setRequestHeader(req, ctx)
+	span := startClientSpan(ctx, path, req.Header)
+	defer span.Finish()
+	if hedge {
+		span.SetAttribute("rpc.hedge", true)
+	}
 
 	resp, err := HTTPClient.Do(req)
 	if err != nil {
-		return err
+		span.SetAttribute("error", err.Error())
+		return nil, false, err
 	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
 	defer resp.Body.Close()
+	c.checkDeprecation(ctx, path, resp.Header)
 
 	if resp.StatusCode != http.StatusOK {
 		var er errReply
 		if err = c.codec.Decode(resp.Body, &er); err != nil {
-			return err
+			return nil, false, err
 		}
-		return clientError(codes.Code(er.Code), er.Cause, er.Stack)
+		return nil, true, decodeError(&er)
 	}
-	if reply != nil {
-		if err = c.codec.Decode(resp.Body, reply); err != nil {
-			return err
-		}
+	if data, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, false, err
 	}
-	return nil
+	return data, true, nil
 }
 
 func (c *Client) setRequestHeader(r *http.Request, ctx context.Context) {
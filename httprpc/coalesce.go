@@ -0,0 +1,142 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+// flightResult 合并调用的结果, body为编码后的reply.
+type flightResult struct {
+	traceID string
+	header  http.Header
+	body    []byte
+	err     error
+}
+
+type flightCall struct {
+	done chan struct{}
+	res  *flightResult
+}
+
+// flightGroup 合并key相同的并发调用
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do 执行fn并返回结果, key相同的调用正在执行时等待其结果, 等待时ctx结束则返回ctx.Err().
+// leader表示fn是否由本次调用执行.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() *flightResult) (res *flightResult, leader bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.res, false, nil
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.res = fn()
+	return c.res, true, nil
+}
+
+// DefaultCoalesceHeaders 默认作为合并键一部分的请求头, 不同调用方的调用不会合并
+var DefaultCoalesceHeaders = []string{xClientID, "Authorization", "Cookie"}
+
+// SetCoalesceHeaders 设置作为合并键一部分的请求头, 如租户头, 替换DefaultCoalesceHeaders.
+// 需在开始服务前调用.
+func (s *Server) SetCoalesceHeaders(headers ...string) {
+	s.coalesceHeaders = headers
+}
+
+func (s *Server) serveCoalesced(ctx context.Context, w http.ResponseWriter, r *http.Request, meth *method, params map[string]string, v callValues) error {
+	var key bytes.Buffer
+	key.WriteString(meth.path)
+	key.WriteByte('|')
+	headers := s.coalesceHeaders
+	if headers == nil {
+		headers = DefaultCoalesceHeaders
+	}
+	for _, h := range headers {
+		key.WriteString(strconv.Quote(r.Header.Get(h)))
+		key.WriteByte('|')
+	}
+	if params != nil {
+		key.WriteString(r.URL.Path)
+		key.WriteByte('|')
+	}
+	if !isNilInterface(meth.args) {
+		if err := s.codec.Encode(&key, v.args.Interface()); err != nil {
+			return NewError(codes.EncodeBodyFail, err)
+		}
+	}
+
+	// 方法的ctx不随请求取消, 等待其它调用的结果时以请求的ctx为准
+	res, leader, err := s.flights.do(r.Context(), key.String(), func() *flightResult {
+		res := &flightResult{}
+		if rctx, ok := ctx.(*Context); ok {
+			res.traceID = rctx.TraceID
+		}
+		if res.err = s.call(ctx, meth, v.args, v.reply); res.err != nil {
+			return res
+		}
+		if rctx, ok := ctx.(*Context); ok {
+			res.header = rctx.ResponseHeader.Clone()
+		}
+		if !isNilInterface(meth.reply) {
+			var buf bytes.Buffer
+			if err := s.codec.Encode(&buf, v.reply.Interface()); err != nil {
+				res.err = NewError(codes.EncodeBodyFail, err)
+				return res
+			}
+			res.body = buf.Bytes()
+		}
+		return res
+	})
+	if err != nil {
+		return err
+	}
+	if !leader {
+		Logger().WithContext(ctx).Debugw("call coalesced", "path", meth.path, "leaderTraceID", res.traceID)
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	s.setResponseHeader(w, ctx, r)
+	if !leader {
+		for key, values := range res.header {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+	}
+	if isNilInterface(meth.reply) {
+		return nil
+	}
+	if r.Method == http.MethodGet {
+		writeCacheable(w, r, res.body)
+		return nil
+	}
+	w.Write(res.body)
+	return nil
+}
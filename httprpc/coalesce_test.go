@@ -0,0 +1,178 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Lookup struct {
+	calls int32
+}
+
+func (l *Lookup) Get(ctx *Context, args *Args, reply *Reply) error {
+	atomic.AddInt32(&l.calls, 1)
+	time.Sleep(200 * time.Millisecond)
+	ctx.SetLastModified(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	reply.C = args.A + args.B
+	return nil
+}
+
+func TestCoalesce(t *testing.T) {
+	var l Lookup
+	s := NewServer(nil)
+	if err := s.Register("/lookup", &l); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetCoalesce("/lookup/NotFound"); err == nil {
+		t.Fatalf("SetCoalesce return error is nil")
+	}
+	if err := s.SetCoalesce("/lookup/Get"); err != nil {
+		t.Fatalf("SetCoalesce: %v", err)
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	recorders := make([]*httptest.ResponseRecorder, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := httptest.NewRequest("POST", "/lookup/Get", bytes.NewReader([]byte(`{"A":1,"B":2}`)))
+			r.Header.Set(xTraceID, fmt.Sprintf("trace-%d", i))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, r)
+			recorders[i] = w
+		}(i)
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&l.calls); got != 1 {
+		t.Errorf("calls: got %d, want 1", got)
+	}
+	for i, w := range recorders {
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"C":3}` {
+			t.Errorf("call%d: status %d, body %s", i, w.Code, w.Body)
+		}
+		if got, want := w.Header().Get(xTraceID), fmt.Sprintf("trace-%d", i); got != want {
+			t.Errorf("call%d: trace id: got %s, want %s", i, got, want)
+		}
+		if w.Header().Get("Last-Modified") == "" {
+			t.Errorf("call%d: Last-Modified header not set", i)
+		}
+	}
+
+	// 参数不同的调用不合并
+	var reply Reply
+	if err := callTestServer(s, "/lookup/Get", Args{A: 2, B: 2}, &reply); err != nil || reply.C != 4 {
+		t.Fatalf("call: %v, reply: %v", err, reply)
+	}
+	if got := atomic.LoadInt32(&l.calls); got != 2 {
+		t.Errorf("calls: got %d, want 2", got)
+	}
+}
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+	res, leader, err := g.do(context.Background(), "a", func() *flightResult { return &flightResult{body: []byte("a")} })
+	if err != nil || !leader || string(res.body) != "a" {
+		t.Fatalf("do: leader %v, res %v, err %v", leader, res, err)
+	}
+	if len(g.calls) != 0 {
+		t.Fatalf("calls not removed: %v", g.calls)
+	}
+
+	// 等待中的调用方在ctx结束时返回
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go g.do(context.Background(), "b", func() *flightResult {
+		close(started)
+		<-release
+		return &flightResult{}
+	})
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, leader, err = g.do(ctx, "b", nil); leader || err != context.DeadlineExceeded {
+		t.Fatalf("do: leader %v, err %v", leader, err)
+	}
+	close(release)
+}
+
+func TestCoalesceHeaders(t *testing.T) {
+	var l Lookup
+	s := NewServer(nil)
+	if err := s.Register("/lookup", &l); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetCoalesce("/lookup/Get"); err != nil {
+		t.Fatalf("SetCoalesce: %v", err)
+	}
+	s.SetCoalesceHeaders(xClientID, "X-Tenant")
+
+	var wg sync.WaitGroup
+	for _, client := range []string{"c1", "c1", "c2"} {
+		for _, tenant := range []string{"t1", "t2"} {
+			wg.Add(1)
+			go func(client, tenant string) {
+				defer wg.Done()
+				r := httptest.NewRequest("POST", "/lookup/Get", strings.NewReader(`{"A":1,"B":2}`))
+				r.Header.Set(xClientID, client)
+				r.Header.Set("X-Tenant", tenant)
+				s.ServeHTTP(httptest.NewRecorder(), r)
+			}(client, tenant)
+		}
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&l.calls); got != 4 {
+		t.Errorf("calls: got %d, want 4", got)
+	}
+}
+
+func TestCoalesceCancel(t *testing.T) {
+	var l Lookup
+	s := NewServer(nil)
+	if err := s.Register("/lookup", &l); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/firmware", Firmware{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetCoalesce("/firmware/Image"); err == nil {
+		t.Fatalf("SetCoalesce(/firmware/Image): return error is nil")
+	}
+	if err := s.SetCoalesce("/lookup/Get"); err != nil {
+		t.Fatalf("SetCoalesce: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/lookup/Get", strings.NewReader(`{"A":1,"B":2}`)))
+	}()
+	for atomic.LoadInt32(&l.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 请求取消后等待中的调用方立即返回
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest("POST", "/lookup/Get", strings.NewReader(`{"A":1,"B":2}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	start := time.Now()
+	s.ServeHTTP(w, r)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("canceled follower returned after %v", elapsed)
+	}
+	if w.Code == http.StatusOK {
+		t.Errorf("canceled follower: status %d, body %s", w.Code, w.Body)
+	}
+	<-done
+}
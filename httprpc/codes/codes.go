@@ -3,6 +3,7 @@ package codes
 import (
 	"fmt"
 	"net/http"
+	"sort"
 )
 
 type Code int
@@ -40,25 +41,57 @@ func (c Code) Status() int {
 }
 
 const (
-	OK      Code = 0
-	Unknown Code = -1
-	Panic   Code = -2
+	OK               Code = 0
+	Unknown          Code = -1
+	Panic            Code = -2
+	Unavailable      Code = -3
+	DeadlineExceeded Code = -4
+	Canceled         Code = -5
 
-	InvalidPath   Code = -101
-	InvalidHeader Code = -102
+	InvalidPath      Code = -101
+	InvalidHeader    Code = -102
+	MethodNotAllowed Code = -103
+	OriginNotAllowed Code = -104
+	Conflict         Code = -105
 
-	EncodeBodyFail Code = -201
-	DecodeBodyFail Code = -202
+	EncodeBodyFail  Code = -201
+	DecodeBodyFail  Code = -202
+	DecodeQueryFail Code = -203
+	BodyTooLarge    Code = -204
+	ChecksumFail    Code = -205
+
+	PermissionDenied Code = -301
 )
 
 func init() {
 	Register(OK, "ok", http.StatusOK)
 	Register(Unknown, "unknown error", http.StatusInternalServerError)
 	Register(Panic, "panic error", http.StatusInternalServerError)
+	Register(Unavailable, "service unavailable", http.StatusServiceUnavailable)
+	Register(DeadlineExceeded, "deadline exceeded", http.StatusGatewayTimeout)
+	Register(Canceled, "request canceled", 499)
 
 	Register(InvalidPath, "invalid url path", http.StatusBadRequest)
 	Register(InvalidHeader, "invalid http header", http.StatusBadRequest)
+	Register(MethodNotAllowed, "http method not allowed", http.StatusMethodNotAllowed)
+	Register(OriginNotAllowed, "cors origin not allowed", http.StatusForbidden)
+	Register(Conflict, "request conflict", http.StatusConflict)
 
 	Register(EncodeBodyFail, "encode http body fail", http.StatusInternalServerError)
 	Register(DecodeBodyFail, "decode http body fail", http.StatusBadRequest)
+	Register(DecodeQueryFail, "decode url query fail", http.StatusBadRequest)
+	Register(BodyTooLarge, "http body too large", http.StatusRequestEntityTooLarge)
+	Register(ChecksumFail, "http body checksum mismatch", http.StatusBadRequest)
+
+	Register(PermissionDenied, "permission denied", http.StatusForbidden)
+}
+
+// Codes 返回所有已注册的错误码, 按从小到大排序.
+func Codes() []Code {
+	list := make([]Code, 0, len(codes))
+	for c := range codes {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
 }
@@ -3,6 +3,9 @@ package httprpc
 import (
 	"context"
 	"net/http"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
 )
 
 type Context struct {
@@ -10,10 +13,47 @@ type Context struct {
 	TraceID        string
 	Request        *http.Request
 	Response       http.ResponseWriter
-	RequestHeader  http.Header // client使用, 该Header中的值将在请求发送时添加到Request中.
-	ResponseHeader http.Header // server使用, 该Header中的值将在响应返回时添加到Response中.
+	RequestHeader  http.Header       // client使用, 该Header中的值将在请求发送时添加到Request中.
+	ResponseHeader http.Header       // server使用, 该Header中的值将在响应返回时添加到Response中.
+	PathParams     map[string]string // server使用, 路径模板中的参数.
+	MethodPath     string            // server使用, 请求路径解析别名后的方法路径.
+
+	method *method // server使用, 有中间件时为请求的方法
 }
 
 func (c *Context) GetTraceID() string {
 	return c.TraceID
 }
+
+func (c *Context) GetSpanID() string {
+	if s := tracing.SpanFromContext(c); s != nil {
+		return s.SpanID().String()
+	}
+	return ""
+}
+
+// PathParam server使用, 返回路径模板中参数name的值.
+func (c *Context) PathParam(name string) string {
+	return c.PathParams[name]
+}
+
+// SetLastModified server使用, 设置Last-Modified响应头, GET请求据此处理If-Modified-Since.
+func (c *Context) SetLastModified(t time.Time) {
+	if c.ResponseHeader == nil {
+		c.ResponseHeader = make(http.Header)
+	}
+	c.ResponseHeader.Set("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// methodPath 返回请求的方法注册时的路径, 别名和路径模板都解析为注册路径.
+func methodPath(ctx context.Context, r *http.Request) string {
+	if rctx, ok := ctx.(*Context); ok {
+		if rctx.method != nil {
+			return rctx.method.path
+		}
+		if rctx.MethodPath != "" {
+			return rctx.MethodPath
+		}
+	}
+	return normalizePath(r.URL.Path)
+}
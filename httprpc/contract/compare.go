@@ -0,0 +1,188 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change 两个契约之间的一处变化
+type Change struct {
+	Breaking bool
+	Where    string // 如 /device/v1/Get args.Items[].Name 或 code -101
+	Desc     string
+}
+
+func (c Change) String() string {
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s: %s", kind, c.Where, c.Desc)
+}
+
+// Breaking 返回changes中是否有不兼容的变化
+func Breaking(changes []Change) bool {
+	for _, c := range changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+type direction int
+
+const (
+	args direction = iota
+	reply
+)
+
+func (d direction) String() string {
+	if d == args {
+		return "args"
+	}
+	return "reply"
+}
+
+type comparer struct {
+	old, cur *Contract
+	changes  []Change
+	visiting map[[2]string]bool
+}
+
+// Compare 返回cur相对old的变化.
+//
+// 删除方法、删除字段、字段类型变化、错误码删除或变化, 以及参数新增必填字段、
+// 参数字段变为必填和返回值字段变为可选都是不兼容的; 新增方法、可选参数字段、返回值字段和错误码是兼容的.
+// 结构体按字段比较, 只修改Go类型名不算变化.
+func Compare(old, cur *Contract) []Change {
+	c := &comparer{old: old, cur: cur, visiting: make(map[[2]string]bool)}
+	c.compareMethods()
+	c.compareCodes()
+	return c.changes
+}
+
+func (c *comparer) add(breaking bool, where, format string, a ...interface{}) {
+	c.changes = append(c.changes, Change{Breaking: breaking, Where: where, Desc: fmt.Sprintf(format, a...)})
+}
+
+func (c *comparer) compareMethods() {
+	methods := make(map[string]Method, len(c.cur.Methods))
+	for _, m := range c.cur.Methods {
+		methods[m.Path] = m
+	}
+	for _, om := range c.old.Methods {
+		m, ok := methods[om.Path]
+		if !ok {
+			c.add(true, om.Path, "method removed")
+			continue
+		}
+		if om.ReadOnly && !m.ReadOnly {
+			c.add(true, om.Path, "no longer read-only, GET is not allowed")
+		} else if !om.ReadOnly && m.ReadOnly {
+			c.add(false, om.Path, "now read-only")
+		}
+		c.compareType(args, om.Path+" args", om.Args, m.Args)
+		c.compareType(reply, om.Path+" reply", om.Reply, m.Reply)
+	}
+	old := make(map[string]bool, len(c.old.Methods))
+	for _, m := range c.old.Methods {
+		old[m.Path] = true
+	}
+	for _, m := range c.cur.Methods {
+		if !old[m.Path] {
+			c.add(false, m.Path, "method added")
+		}
+	}
+}
+
+func (c *comparer) compareType(dir direction, where, o, n string) {
+	for {
+		if strings.HasPrefix(o, "[]") && strings.HasPrefix(n, "[]") {
+			o, n, where = o[2:], n[2:], where+"[]"
+		} else if strings.HasPrefix(o, "map[string]") && strings.HasPrefix(n, "map[string]") {
+			o, n, where = o[11:], n[11:], where+"[]"
+		} else {
+			break
+		}
+	}
+	oldFields, oldStruct := c.old.Types[o]
+	curFields, curStruct := c.cur.Types[n]
+	if oldStruct && curStruct {
+		c.compareFields(dir, where, o, n, oldFields, curFields)
+		return
+	}
+	if oldStruct || curStruct || o != n {
+		c.add(true, where, "type changed from %s to %s", o, n)
+	}
+}
+
+func (c *comparer) compareFields(dir direction, where, oldName, curName string, oldFields, curFields []Field) {
+	// 递归类型只比较一次
+	key := [2]string{oldName, curName}
+	if c.visiting[key] {
+		return
+	}
+	c.visiting[key] = true
+	defer delete(c.visiting, key)
+
+	fields := make(map[string]Field, len(curFields))
+	for _, f := range curFields {
+		fields[f.Name] = f
+	}
+	for _, of := range oldFields {
+		at := where + "." + of.Name
+		f, ok := fields[of.Name]
+		if !ok {
+			c.add(true, at, "field removed")
+			continue
+		}
+		if dir == args && of.Optional && !f.Optional {
+			c.add(true, at, "field became required")
+		} else if dir == reply && !of.Optional && f.Optional {
+			c.add(true, at, "field became optional")
+		}
+		c.compareType(dir, at, of.Type, f.Type)
+	}
+	old := make(map[string]bool, len(oldFields))
+	for _, f := range oldFields {
+		old[f.Name] = true
+	}
+	for _, f := range curFields {
+		if old[f.Name] {
+			continue
+		}
+		if dir == args && !f.Optional {
+			c.add(true, where+"."+f.Name, "required field added")
+		} else {
+			c.add(false, where+"."+f.Name, "field added")
+		}
+	}
+}
+
+func (c *comparer) compareCodes() {
+	codes := make(map[int]Code, len(c.cur.Codes))
+	for _, code := range c.cur.Codes {
+		codes[int(code.Code)] = code
+	}
+	for _, oc := range c.old.Codes {
+		where := fmt.Sprintf("code %d", oc.Code)
+		code, ok := codes[int(oc.Code)]
+		if !ok {
+			c.add(true, where, "code removed")
+			continue
+		}
+		if oc.Desc != code.Desc {
+			c.add(true, where, "desc changed from %q to %q", oc.Desc, code.Desc)
+		}
+		if oc.Status != code.Status {
+			c.add(true, where, "status changed from %d to %d", oc.Status, code.Status)
+		}
+		delete(codes, int(oc.Code))
+	}
+	for _, code := range c.cur.Codes {
+		if _, ok := codes[int(code.Code)]; ok {
+			c.add(false, fmt.Sprintf("code %d", code.Code), "code added")
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package contract 生成httprpc服务的接口契约, 并与之前保存的契约对比以发现不兼容的变化.
+//
+// 契约包含方法路径、参数和返回值经编码后的结构(JSON字段名和类型)以及错误码.
+// 在测试中使用contracttest.Check与保存的契约对比.
+package contract
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/internal/jsonfield"
+)
+
+// Contract 服务的接口契约.
+//
+// 类型使用字符串表示: 基本类型为bool、string、int64、float64等, time为时间, bytes为[]byte,
+// any为interface{}或自定义编码的类型, stream为流式参数或返回值, file为上传的文件;
+// 数组为[]T, map为map[string]T, 结构体为Types中的名字. 指针与其指向的类型相同.
+type Contract struct {
+	Methods []Method
+	Types   map[string][]Field `json:",omitempty"`
+	Codes   []Code
+}
+
+// Method 方法的契约
+type Method struct {
+	Path     string
+	Args     string
+	Reply    string
+	ReadOnly bool `json:",omitempty"`
+}
+
+// Field 结构体字段, Optional为omitempty或指针类型的字段
+type Field struct {
+	Name     string
+	Type     string
+	Optional bool `json:",omitempty"`
+}
+
+// Code 契约中的错误码
+type Code struct {
+	Code   codes.Code
+	Desc   string
+	Status int
+}
+
+var (
+	typeOfTime          = reflect.TypeOf(time.Time{})
+	typeOfRawMessage    = reflect.TypeOf(json.RawMessage{})
+	typeOfJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeOfReader        = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	typeOfReadCloser    = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+	typeOfStream        = reflect.TypeOf(httprpc.Stream{})
+	typeOfFileHeader    = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// 基本类型的名字, 结构体不能使用
+var builtins = map[string]bool{
+	"bool": true, "string": true, "time": true, "bytes": true, "any": true, "stream": true, "file": true,
+	"int8": true, "int16": true, "int32": true, "int64": true,
+	"uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// 内置错误码的范围, 由codes包注册, 与程序引入的其他包无关
+const (
+	minBuiltinCode = -399
+	maxBuiltinCode = 0
+)
+
+// Options 契约生成选项.
+//
+// 契约只包含httprpc内置的错误码(-399到0)和Codes中列出的错误码;
+// 已注册的全部错误码取决于程序引入了哪些包, 不适合用于对比.
+type Options struct {
+	Codes []codes.Code
+}
+
+func (o *Options) hasCode(c codes.Code) bool {
+	if c >= minBuiltinCode && c <= maxBuiltinCode {
+		return true
+	}
+	for _, code := range o.Codes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Snapshot 生成Server中已注册方法和错误码的契约
+func Snapshot(s *httprpc.Server, opts Options) *Contract {
+	b := &builder{
+		types: make(map[string][]Field),
+		names: make(map[reflect.Type]string),
+	}
+	c := &Contract{}
+	for _, m := range s.Methods() {
+		c.Methods = append(c.Methods, Method{
+			Path:     m.Path,
+			Args:     b.typeOf(m.Args),
+			Reply:    b.typeOf(m.Reply),
+			ReadOnly: m.ReadOnly,
+		})
+	}
+	if len(b.types) > 0 {
+		c.Types = b.types
+	}
+	for _, code := range codes.Codes() {
+		if opts.hasCode(code) {
+			c.Codes = append(c.Codes, Code{Code: code, Desc: code.String(), Status: code.Status()})
+		}
+	}
+	return c
+}
+
+type builder struct {
+	types map[string][]Field
+	names map[reflect.Type]string
+	anons int
+}
+
+func (b *builder) typeOf(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == typeOfTime:
+		return "time"
+	case t == typeOfStream || t == typeOfReader || t == typeOfReadCloser:
+		return "stream"
+	case t == typeOfFileHeader:
+		return "file"
+	case t == typeOfRawMessage:
+		return "any"
+	case t.Implements(typeOfJSONMarshaler) || reflect.PtrTo(t).Implements(typeOfJSONMarshaler):
+		return "any"
+	case t.Implements(typeOfTextMarshaler) || reflect.PtrTo(t).Implements(typeOfTextMarshaler):
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return t.Kind().String()
+	case reflect.Int:
+		return "int64"
+	case reflect.Uint, reflect.Uintptr:
+		return "uint64"
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		return "[]" + b.typeOf(t.Elem())
+	case reflect.Map:
+		return "map[string]" + b.typeOf(t.Elem())
+	case reflect.Struct:
+		return b.structOf(t)
+	}
+	return "any"
+}
+
+func (b *builder) structOf(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := b.typeName(t)
+	b.names[t] = name
+	// 先占位, 避免递归类型无限展开
+	b.types[name] = nil
+	fields := []Field{}
+	for _, f := range jsonfield.Fields(t) {
+		fields = append(fields, Field{
+			Name:     f.Name,
+			Type:     b.typeOf(f.Type),
+			Optional: f.OmitEmpty || f.Type.Kind() == reflect.Ptr,
+		})
+	}
+	b.types[name] = fields
+	return name
+}
+
+func (b *builder) typeName(t reflect.Type) string {
+	if t.Name() == "" {
+		b.anons++
+		return "struct" + strconv.Itoa(b.anons)
+	}
+	name := t.Name()
+	if b.free(name) {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	base := pkg + "." + name
+	name = base
+	for i := 2; !b.free(name); i++ {
+		name = base + strconv.Itoa(i)
+	}
+	return name
+}
+
+func (b *builder) free(name string) bool {
+	_, ok := b.types[name]
+	return !ok && !builtins[name]
+}
+
+// WriteJSON 将契约编码为JSON, 输出稳定, 适合保存在代码仓库中
+func (c *Contract) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// Read 读取WriteJSON写入的契约
+func Read(r io.Reader) (*Contract, error) {
+	var c Contract
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Handler 返回输出契约的http.Handler, 每次请求时根据Server的当前状态生成.
+func Handler(s *httprpc.Server, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		Snapshot(s, opts).WriteJSON(w)
+	})
+}
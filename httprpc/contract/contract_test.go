@@ -0,0 +1,199 @@
+package contract
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type Address struct {
+	City string
+	Zip  string `json:",omitempty"`
+}
+
+type User struct {
+	ID      int64
+	Name    string
+	Email   string `json:"email"`
+	Addr    *Address
+	Friends []User `json:",omitempty"`
+	Created time.Time
+	Meta    struct{ Tags map[string]int }
+}
+
+type GetArgs struct {
+	ID int
+}
+
+type UserService struct{}
+
+func (UserService) Get(ctx context.Context, args *GetArgs, reply *User) error {
+	return nil
+}
+
+func (UserService) Delete(ctx context.Context, args *GetArgs, reply *struct{}) error {
+	return nil
+}
+
+type AddressV2 struct {
+	City string
+	Zip  int `json:",omitempty"`
+}
+
+type UserV2 struct {
+	ID      int64
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Addr    *AddressV2
+	Friends []UserV2 `json:",omitempty"`
+	Created time.Time
+	Meta    struct{ Tags map[string]int }
+	Phone   string `json:",omitempty"`
+}
+
+type GetArgsV2 struct {
+	ID      int64
+	Verbose bool `json:",omitempty"`
+	Tenant  string
+}
+
+type UserServiceV2 struct{}
+
+func (UserServiceV2) Get(ctx context.Context, args *GetArgsV2, reply *UserV2) error {
+	return nil
+}
+
+func (UserServiceV2) List(ctx context.Context) ([]UserV2, error) {
+	return nil, nil
+}
+
+// appCode 应用注册的错误码, 只在Options中列出时记录
+const appCode codes.Code = -9001
+
+func init() {
+	codes.Register(appCode, "app error", 409)
+}
+
+func newServer(t testing.TB, rcvr interface{}) *httprpc.Server {
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/user", rcvr); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return s
+}
+
+func TestSnapshot(t *testing.T) {
+	s := newServer(t, UserService{})
+	if err := s.SetReadOnly("/user/Get"); err != nil {
+		t.Fatalf("SetReadOnly: %v", err)
+	}
+	c := Snapshot(s, Options{})
+
+	methods := []Method{
+		{Path: "/user/Delete", Args: "GetArgs", Reply: "struct1"},
+		{Path: "/user/Get", Args: "GetArgs", Reply: "User", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(c.Methods, methods) {
+		t.Fatalf("methods: got %+v, want %+v", c.Methods, methods)
+	}
+	types := map[string][]Field{
+		"GetArgs": {{Name: "ID", Type: "int64"}},
+		"User": {
+			{Name: "ID", Type: "int64"},
+			{Name: "Name", Type: "string"},
+			{Name: "email", Type: "string"},
+			{Name: "Addr", Type: "Address", Optional: true},
+			{Name: "Friends", Type: "[]User", Optional: true},
+			{Name: "Created", Type: "time"},
+			{Name: "Meta", Type: "struct2"},
+		},
+		"Address": {{Name: "City", Type: "string"}, {Name: "Zip", Type: "string", Optional: true}},
+		"struct2": {{Name: "Tags", Type: "map[string]int64"}},
+		"struct1": {},
+	}
+	if !reflect.DeepEqual(c.Types, types) {
+		t.Fatalf("types: got %+v, want %+v", c.Types, types)
+	}
+	for _, code := range c.Codes {
+		if code.Code == appCode {
+			t.Fatalf("codes: unlisted code %d recorded", appCode)
+		}
+	}
+	if got, want := c.Codes[0], (Code{Code: codes.PermissionDenied, Desc: "permission denied", Status: 403}); got != want {
+		t.Fatalf("codes: got %+v, want %+v", got, want)
+	}
+
+	c = Snapshot(s, Options{Codes: []codes.Code{appCode}})
+	if got, want := c.Codes[0], (Code{Code: appCode, Desc: "app error", Status: 409}); got != want {
+		t.Fatalf("codes: got %+v, want %+v", got, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := Snapshot(newServer(t, UserService{}), Options{})
+	old.Codes = append(old.Codes, Code{Code: -9999, Desc: "gone", Status: 500})
+	for i := range old.Codes {
+		if old.Codes[i].Code == codes.InvalidPath {
+			old.Codes[i].Status = 404
+		}
+	}
+	cur := Snapshot(newServer(t, UserServiceV2{}), Options{})
+
+	want := []string{
+		"breaking: /user/Delete: method removed",
+		"compatible: /user/Get args.Verbose: field added",
+		"breaking: /user/Get args.Tenant: required field added",
+		"breaking: /user/Get reply.Name: field removed",
+		"breaking: /user/Get reply.Addr.Zip: type changed from string to int64",
+		"compatible: /user/Get reply.name: field added",
+		"compatible: /user/Get reply.Phone: field added",
+		"compatible: /user/List: method added",
+		"breaking: code -101: status changed from 404 to 400",
+		"breaking: code -9999: code removed",
+	}
+	var got []string
+	for _, c := range Compare(old, cur) {
+		got = append(got, c.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes:\ngot  %q\nwant %q", got, want)
+	}
+
+	if changes := Compare(cur, Snapshot(newServer(t, UserServiceV2{}), Options{})); len(changes) != 0 {
+		t.Fatalf("unexpected changes: %v", changes)
+	}
+}
+
+func TestCompareFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      direction
+		old, cur Field
+		want     string
+	}{
+		{name: "args optional to required", dir: args, old: Field{Type: "string", Optional: true}, cur: Field{Type: "string"}, want: "breaking: x.F: field became required"},
+		{name: "args required to optional", dir: args, old: Field{Type: "string"}, cur: Field{Type: "string", Optional: true}},
+		{name: "reply required to optional", dir: reply, old: Field{Type: "string"}, cur: Field{Type: "string", Optional: true}, want: "breaking: x.F: field became optional"},
+		{name: "slice to map", dir: reply, old: Field{Type: "[]int64"}, cur: Field{Type: "map[string]int64"}, want: "breaking: x.F: type changed from []int64 to map[string]int64"},
+		{name: "map element", dir: reply, old: Field{Type: "map[string][]int64"}, cur: Field{Type: "map[string][]string"}, want: "breaking: x.F[][]: type changed from int64 to string"},
+	}
+	for _, tt := range tests {
+		tt.old.Name, tt.cur.Name = "F", "F"
+		c := &comparer{old: &Contract{}, cur: &Contract{}, visiting: make(map[[2]string]bool)}
+		c.compareFields(tt.dir, "x", "T", "T", []Field{tt.old}, []Field{tt.cur})
+		got := fmt.Sprint(c.changes)
+		if tt.want != "" {
+			tt.want = "[" + tt.want + "]"
+		} else {
+			tt.want = "[]"
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package contracttest 在测试中检查httprpc.Server的契约是否有不兼容的变化.
+package contracttest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/contract"
+)
+
+// UpdateEnv 设置该环境变量时, Check用当前契约覆盖golden文件
+const UpdateEnv = "HTTPRPC_CONTRACT_UPDATE"
+
+// Check 在测试中将Server的契约与golden文件对比, 有不兼容的变化时测试失败, 兼容的变化只输出日志.
+// golden文件不存在或设置了环境变量HTTPRPC_CONTRACT_UPDATE时写入当前契约.
+//
+//	func TestContract(t *testing.T) {
+//		contracttest.Check(t, newServer(), "testdata/contract.json", contract.Options{})
+//	}
+func Check(t testing.TB, s *httprpc.Server, golden string, opts contract.Options) {
+	t.Helper()
+	cur := contract.Snapshot(s, opts)
+	if os.Getenv(UpdateEnv) == "" {
+		old, err := readFile(golden)
+		if err == nil {
+			changes := contract.Compare(old, cur)
+			for _, c := range changes {
+				if c.Breaking {
+					t.Errorf("%s", c)
+				} else {
+					t.Logf("%s", c)
+				}
+			}
+			if len(changes) > 0 {
+				t.Logf("contract %s changed, run tests with %s=1 to accept the changes", golden, UpdateEnv)
+			}
+			return
+		}
+		if !os.IsNotExist(err) {
+			t.Fatalf("read contract: %v", err)
+		}
+	}
+	if err := writeFile(golden, cur); err != nil {
+		t.Fatalf("write contract: %v", err)
+	}
+	t.Logf("contract %s written", golden)
+}
+
+func readFile(name string) (*contract.Contract, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return contract.Read(f)
+}
+
+func writeFile(name string, c *contract.Contract) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := c.WriteJSON(&buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
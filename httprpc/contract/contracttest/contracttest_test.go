@@ -0,0 +1,92 @@
+package contracttest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/contract"
+)
+
+type GetArgs struct {
+	ID int
+}
+
+type User struct {
+	ID   int64
+	Name string
+}
+
+type UserService struct{}
+
+func (UserService) Get(ctx context.Context, args *GetArgs, reply *User) error {
+	return nil
+}
+
+type GetArgsV2 struct {
+	ID     int64
+	Tenant string
+}
+
+type UserV2 struct {
+	ID   int64
+	Name string `json:",omitempty"`
+}
+
+type UserServiceV2 struct{}
+
+func (UserServiceV2) Get(ctx context.Context, args *GetArgsV2, reply *UserV2) error {
+	return nil
+}
+
+func newServer(t testing.TB, rcvr interface{}) *httprpc.Server {
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/user", rcvr); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return s
+}
+
+type recorder struct {
+	testing.TB
+	errors int
+}
+
+func (r *recorder) Helper()                                   {}
+func (r *recorder) Logf(format string, args ...interface{})   {}
+func (r *recorder) Errorf(format string, args ...interface{}) { r.errors++ }
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "contract")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "testdata", "contract.json")
+
+	r := &recorder{TB: t}
+	Check(r, newServer(t, UserService{}), golden, contract.Options{})
+	if _, err := os.Stat(golden); err != nil {
+		t.Fatalf("golden not written: %v", err)
+	}
+	Check(r, newServer(t, UserService{}), golden, contract.Options{})
+	if r.errors != 0 {
+		t.Fatalf("errors: got %d, want 0", r.errors)
+	}
+	Check(r, newServer(t, UserServiceV2{}), golden, contract.Options{})
+	if r.errors != 2 {
+		t.Fatalf("errors: got %d, want 2", r.errors)
+	}
+
+	os.Setenv(UpdateEnv, "1")
+	Check(r, newServer(t, UserServiceV2{}), golden, contract.Options{})
+	os.Unsetenv(UpdateEnv)
+	r.errors = 0
+	Check(r, newServer(t, UserServiceV2{}), golden, contract.Options{})
+	if r.errors != 0 {
+		t.Fatalf("errors after update: got %d, want 0", r.errors)
+	}
+}
@@ -0,0 +1,183 @@
+package httprpc
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
+)
+
+// CORS 跨域访问策略.
+//
+// AllowedOrigins支持精确匹配"https://a.example.com", 子域名通配"https://*.example.com"
+// 以及"*", "*"不能与AllowCredentials同时使用; AllowedHeaders包含"*"时允许预检请求中的所有请求头.
+type CORS struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// NewCORS 返回允许origins访问的默认策略, 允许本包使用的请求头, 暴露X-Trace-Id响应头.
+func NewCORS(origins ...string) *CORS {
+	return &CORS{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Content-Type", xTraceID, xClientID, tracing.HeaderTraceparent, tracing.HeaderTracestate,
+			xIdempotencyKey, xPriority, xChecksum, "X-Verbose"},
+		ExposedHeaders: []string{xTraceID},
+		MaxAge:         10 * time.Minute,
+	}
+}
+
+// SetCORS 设置跨域访问策略, 为nil时不输出任何CORS响应头.
+func (s *Server) SetCORS(c *CORS) error {
+	if c != nil {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	s.cors = c
+	return nil
+}
+
+// Validate 检查策略, 允许任意来源时不能携带凭据, 否则任意网站都能以用户身份调用.
+func (c *CORS) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			return errors.New("cors: wildcard origin can not be used with credentials")
+		}
+	}
+	return nil
+}
+
+func (c *CORS) allowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range c.AllowedOrigins {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if i := strings.Index(pattern, "://*."); i >= 0 {
+			prefix, suffix := pattern[:i+3], pattern[i+4:]
+			if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				sub := origin[len(prefix) : len(origin)-len(suffix)]
+				if sub != "" && !strings.ContainsAny(sub, "/:") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowMethod(method string) bool {
+	for _, m := range c.AllowedMethods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowHeaders(headers string) bool {
+	for _, h := range strings.Split(headers, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		allowed := false
+		for _, a := range c.AllowedHeaders {
+			if a == "*" || strings.EqualFold(a, h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CORS) setOrigin(h http.Header, origin string) {
+	h.Add("Vary", "Origin")
+	if !c.AllowCredentials && len(c.AllowedOrigins) == 1 && c.AllowedOrigins[0] == "*" {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	// SetCORS之后修改了策略时也不对任意来源开放凭据
+	if c.AllowCredentials && c.Validate() == nil {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// isSameOrigin 浏览器对同源的POST请求也会携带Origin头, 这类请求不受跨域策略限制.
+func isSameOrigin(origin string, r *http.Request) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// checkOrigin 检查实际请求的Origin, 并设置CORS响应头.
+func (s *Server) checkOrigin(h http.Header, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if s.cors == nil || origin == "" || isSameOrigin(origin, r) {
+		return nil
+	}
+	if !s.cors.allowOrigin(origin) {
+		return Errorf(codes.OriginNotAllowed, "origin %s is not allowed", origin)
+	}
+	s.cors.setOrigin(h, origin)
+	if len(s.cors.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(s.cors.ExposedHeaders, ", "))
+	}
+	return nil
+}
+
+// preflight 处理OPTIONS预检请求
+func (s *Server) preflight(w http.ResponseWriter, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+	if s.cors == nil || origin == "" || method == "" {
+		w.Header().Set("Allow", "OPTIONS, GET, POST")
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	c := s.cors
+	if !c.allowOrigin(origin) {
+		return Errorf(codes.OriginNotAllowed, "origin %s is not allowed", origin)
+	}
+	if !c.allowMethod(method) {
+		return Errorf(codes.OriginNotAllowed, "method %s is not allowed for origin %s", method, origin)
+	}
+	headers := r.Header.Get("Access-Control-Request-Headers")
+	if !c.allowHeaders(headers) {
+		return Errorf(codes.OriginNotAllowed, "headers %s are not allowed for origin %s", headers, origin)
+	}
+
+	h := w.Header()
+	c.setOrigin(h, origin)
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+	if headers != "" {
+		h.Set("Access-Control-Allow-Headers", headers)
+	}
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,103 @@
+package httprpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCORSAllowOrigin(t *testing.T) {
+	c := NewCORS("https://a.example.com", "https://*.test.com")
+	tests := []struct {
+		origin string
+		allow  bool
+	}{
+		{origin: "https://a.example.com", allow: true},
+		{origin: "https://A.Example.com", allow: true},
+		{origin: "http://a.example.com", allow: false},
+		{origin: "https://b.example.com", allow: false},
+		{origin: "https://x.test.com", allow: true},
+		{origin: "https://x.y.test.com", allow: true},
+		{origin: "https://test.com", allow: false},
+		{origin: "https://x.test.com:8080", allow: false},
+		{origin: "https://evil.com/.test.com", allow: false},
+	}
+	for _, tt := range tests {
+		if got, want := c.allowOrigin(tt.origin), tt.allow; got != want {
+			t.Errorf("allowOrigin(%s): got %v, want %v", tt.origin, got, want)
+		}
+	}
+}
+
+func TestServerCORS(t *testing.T) {
+	var a Arith
+	s := NewServer(nil)
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	request := func(method, origin string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/arith/Add", strings.NewReader(`{"A":1,"B":2}`))
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	// 未设置策略, 不输出CORS头
+	w := request("POST", "https://evil.com", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("no policy: status %d, header %v", w.Code, w.Header())
+	}
+
+	wildcard := NewCORS("*")
+	wildcard.AllowCredentials = true
+	if err := s.SetCORS(wildcard); err == nil {
+		t.Fatalf("SetCORS: wildcard origin with credentials return nil error")
+	}
+
+	c := NewCORS("https://a.example.com")
+	c.AllowCredentials = true
+	if err := s.SetCORS(c); err != nil {
+		t.Fatalf("SetCORS: %v", err)
+	}
+
+	preflight := http.Header{
+		"Access-Control-Request-Method":  {"POST"},
+		"Access-Control-Request-Headers": {"content-type, x-trace-id, traceparent, x-idempotency-key, x-priority, x-checksum-sha256"},
+	}
+	tests := []struct {
+		method string
+		origin string
+		header http.Header
+		status int
+		allow  string
+	}{
+		{method: "OPTIONS", origin: "https://a.example.com", header: preflight, status: http.StatusNoContent, allow: "https://a.example.com"},
+		{method: "OPTIONS", origin: "https://b.example.com", header: preflight, status: http.StatusForbidden},
+		{method: "OPTIONS", origin: "https://a.example.com", header: http.Header{"Access-Control-Request-Method": {"PUT"}}, status: http.StatusForbidden},
+		{method: "OPTIONS", origin: "https://a.example.com", header: http.Header{"Access-Control-Request-Method": {"POST"}, "Access-Control-Request-Headers": {"x-other"}}, status: http.StatusForbidden},
+		{method: "POST", origin: "https://a.example.com", status: http.StatusOK, allow: "https://a.example.com"},
+		{method: "POST", origin: "https://b.example.com", status: http.StatusForbidden},
+		{method: "POST", origin: "http://example.com", status: http.StatusOK},
+		{method: "POST", origin: "", status: http.StatusOK},
+	}
+	for i, tt := range tests {
+		w := request(tt.method, tt.origin, tt.header)
+		if got, want := w.Code, tt.status; got != want {
+			t.Errorf("case%d: status: got %v, want %v, body: %s", i, got, want, w.Body)
+		}
+		if got, want := w.Header().Get("Access-Control-Allow-Origin"), tt.allow; got != want {
+			t.Errorf("case%d: Access-Control-Allow-Origin: got %v, want %v", i, got, want)
+		}
+		if tt.allow != "" && w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("case%d: Access-Control-Allow-Credentials not set", i)
+		}
+	}
+}
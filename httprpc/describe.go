@@ -0,0 +1,85 @@
+package httprpc
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// MethodInfo 已注册方法的描述信息
+type MethodInfo struct {
+	Path     string
+	Class    string
+	Name     string
+	Args     reflect.Type
+	Reply    reflect.Type
+	ReadOnly bool
+
+	Alias      string   // 不为空时, Path为Alias方法的别名
+	PathParams []string // 路径参数名, 如/devices/{id}/Reboot的id
+	Deprecated bool
+	Sunset     time.Time
+}
+
+// Methods 返回所有已注册的方法, 按路径排序.
+func (s *Server) Methods() []MethodInfo {
+	var methods []MethodInfo
+	s.classes.Range(func(key, value interface{}) bool {
+		c := value.(*class)
+		for name, m := range c.methods {
+			methods = append(methods, MethodInfo{
+				Path:     joinPath(c.name, name),
+				Class:    c.name,
+				Name:     name,
+				Args:     m.args,
+				Reply:    m.reply,
+				ReadOnly: m.readOnly,
+			})
+		}
+		return true
+	})
+	s.aliases.Range(func(key, value interface{}) bool {
+		alias, target := key.(string), value.(string)
+		if v, ok := s.classes.Load(target); ok {
+			c := v.(*class)
+			for name, m := range c.methods {
+				path := joinPath(alias, name)
+				if _, ok := s.aliases.Load(path); ok {
+					continue
+				}
+				methods = append(methods, newAliasInfo(path, alias, name, joinPath(target, name), m))
+			}
+			return true
+		}
+		className, name := splitPath(alias)
+		if _, m, err := s.lookupByPath(target); err == nil {
+			methods = append(methods, newAliasInfo(alias, className, name, target, m))
+		}
+		return true
+	})
+	for i := range methods {
+		methods[i].PathParams = pathParams(methods[i].Path)
+		if _, d := s.lookupDeprecation(methods[i].Path); d != nil {
+			methods[i].Deprecated = true
+			methods[i].Sunset = d.sunset
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Path < methods[j].Path })
+	return methods
+}
+
+func newAliasInfo(path, class, name, target string, m *method) MethodInfo {
+	return MethodInfo{
+		Path:     path,
+		Class:    class,
+		Name:     name,
+		Args:     m.args,
+		Reply:    m.reply,
+		ReadOnly: m.readOnly,
+		Alias:    target,
+	}
+}
+
+func (s *Server) Codec() Codec {
+	return s.codec
+}
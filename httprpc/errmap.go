@@ -0,0 +1,100 @@
+package httprpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+// ErrorMapFunc 将错误转换为错误码, 无法转换时返回false.
+type ErrorMapFunc func(err error) (codes.Code, bool)
+
+// ErrorMapper 错误码映射表, 按注册顺序匹配.
+type ErrorMapper struct {
+	mu    sync.RWMutex
+	funcs []ErrorMapFunc
+}
+
+func NewErrorMapper() *ErrorMapper {
+	return &ErrorMapper{}
+}
+
+// DefaultErrorMapper 默认映射表, 包含context错误以及被包装的ErrorCode错误.
+var DefaultErrorMapper = NewErrorMapper()
+
+func init() {
+	DefaultErrorMapper.Func(func(err error) (codes.Code, bool) {
+		var e ErrorCode
+		if errors.As(err, &e) {
+			return e.Code(), true
+		}
+		return 0, false
+	})
+	DefaultErrorMapper.Is(context.DeadlineExceeded, codes.DeadlineExceeded)
+	DefaultErrorMapper.Is(context.Canceled, codes.Canceled)
+}
+
+// Func 注册映射函数
+func (m *ErrorMapper) Func(f ErrorMapFunc) {
+	m.mu.Lock()
+	m.funcs = append(m.funcs, f)
+	m.mu.Unlock()
+}
+
+// Is 将errors.Is(err, target)为true的错误映射为code
+func (m *ErrorMapper) Is(target error, code codes.Code) {
+	m.Func(func(err error) (codes.Code, bool) {
+		return code, errors.Is(err, target)
+	})
+}
+
+// As 将errors.As可以转换为target类型的错误映射为code, target为该类型的零值,
+// 例如(*os.PathError)(nil).
+func (m *ErrorMapper) As(target interface{}, code codes.Code) {
+	typ := reflect.TypeOf(target)
+	if typ == nil || !typ.Implements(typeOfError) {
+		panic("httprpc: ErrorMapper.As target must be a non-nil type implementing error")
+	}
+	m.Func(func(err error) (codes.Code, bool) {
+		return code, errors.As(err, reflect.New(typ).Interface())
+	})
+}
+
+// Map 返回err对应的错误码
+func (m *ErrorMapper) Map(err error) (codes.Code, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, f := range m.funcs {
+		if code, ok := f(err); ok {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// SetErrorMapper 设置方法错误的映射表, 先于DefaultErrorMapper匹配.
+func (s *Server) SetErrorMapper(m *ErrorMapper) {
+	s.errorMapper = m
+}
+
+// mapError 将没有错误码的错误按映射表转换为Error, 无法转换时原样返回.
+func (s *Server) mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(ErrorCode); ok {
+		return err
+	}
+	if s.errorMapper != nil {
+		if code, ok := s.errorMapper.Map(err); ok {
+			return NewError(code, err)
+		}
+	}
+	if code, ok := DefaultErrorMapper.Map(err); ok {
+		return NewError(code, err)
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package httprpc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type notFoundError struct {
+	name string
+}
+
+func (e *notFoundError) Error() string {
+	return e.name + " not found"
+}
+
+func TestErrorMapper(t *testing.T) {
+	const (
+		codeNoRows   codes.Code = -10001
+		codeNotFound codes.Code = -10002
+		codeNoFile   codes.Code = -10003
+	)
+	m := NewErrorMapper()
+	m.Is(sql.ErrNoRows, codeNoRows)
+	m.As((*notFoundError)(nil), codeNotFound)
+	m.Func(func(err error) (codes.Code, bool) {
+		return codeNoFile, os.IsNotExist(err)
+	})
+
+	tests := []struct {
+		err  error
+		code codes.Code
+		ok   bool
+	}{
+		{err: sql.ErrNoRows, code: codeNoRows, ok: true},
+		{err: fmt.Errorf("query: %w", sql.ErrNoRows), code: codeNoRows, ok: true},
+		{err: fmt.Errorf("get: %w", &notFoundError{name: "x"}), code: codeNotFound, ok: true},
+		{err: os.ErrNotExist, code: codeNoFile, ok: true},
+		{err: errors.New("other"), ok: false},
+	}
+	for i, tt := range tests {
+		code, ok := m.Map(tt.err)
+		if ok != tt.ok || code != tt.code {
+			t.Errorf("case%d: Map(%v): got (%v, %v), want (%v, %v)", i, tt.err, code, ok, tt.code, tt.ok)
+		}
+	}
+}
+
+type Failer struct {
+	err error
+}
+
+func (f *Failer) Fail(ctx context.Context, args *Args, reply *Reply) error {
+	return f.err
+}
+
+func TestServerMapError(t *testing.T) {
+	var f Failer
+	s := NewServer(nil)
+	if err := s.Register("/failer", &f); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	m := NewErrorMapper()
+	m.Is(sql.ErrNoRows, codes.InvalidPath)
+	s.SetErrorMapper(m)
+
+	tests := []struct {
+		err    error
+		code   codes.Code
+		status int
+	}{
+		{err: context.DeadlineExceeded, code: codes.DeadlineExceeded, status: http.StatusGatewayTimeout},
+		{err: fmt.Errorf("wait: %w", context.Canceled), code: codes.Canceled, status: 499},
+		{err: fmt.Errorf("wrapped: %w", Errorf(codes.Conflict, "conflict")), code: codes.Conflict, status: http.StatusConflict},
+		{err: sql.ErrNoRows, code: codes.InvalidPath, status: http.StatusBadRequest},
+		{err: errors.New("other"), code: codes.Unknown, status: http.StatusInternalServerError},
+	}
+	for i, tt := range tests {
+		f.err = tt.err
+		r, _ := serveTestHTTP(s, "POST", "/failer/Fail", []byte(`{}`))
+		if r.Code != tt.status {
+			t.Errorf("case%d: status: got %d, want %d", i, r.Code, tt.status)
+		}
+		var er errReply
+		if err := s.codec.Decode(r.Body, &er); err != nil {
+			t.Fatalf("case%d: decode: %v", i, err)
+		}
+		if codes.Code(er.Code) != tt.code || er.Cause != tt.err.Error() {
+			t.Errorf("case%d: got %+v, want code %v, cause %v", i, er, tt.code, tt.err)
+		}
+	}
+}
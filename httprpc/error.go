@@ -7,31 +7,27 @@ import (
 	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
 )
 
+// StackTrace 为true且Server未设置StackPolicy时, 错误栈返回给所有调用方.
+//
+// Deprecated: 使用Server.SetStackPolicy.
 var StackTrace = false
 
 type Error struct {
 	code  codes.Code
 	cause error
+	pcs   []uintptr
 	stack []byte
 }
 
 func NewError(code codes.Code, cause error) error {
-	var stack []byte
-	if StackTrace {
-		stack = runtimeStack()
-	}
-	return &Error{code: code, cause: cause, stack: stack}
+	return &Error{code: code, cause: cause, pcs: callers(3)}
 }
 
 func Errorf(code codes.Code, format string, a ...interface{}) error {
-	var stack []byte
-	if StackTrace {
-		stack = runtimeStack()
-	}
 	return &Error{
 		code:  code,
 		cause: fmt.Errorf(format, a...),
-		stack: stack,
+		pcs:   callers(3),
 	}
 }
 
@@ -51,7 +47,16 @@ func (e *Error) Cause() error {
 	return e.cause
 }
 
+// Unwrap 使errors.Is/As可以匹配cause
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Stack 返回错误创建时的调用栈, 按DefaultStackFilter裁剪.
 func (e *Error) Stack() []byte {
+	if e.stack == nil && len(e.pcs) > 0 {
+		return DefaultStackFilter.format(e.pcs)
+	}
 	return e.stack
 }
 
@@ -0,0 +1,64 @@
+package httprpc
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+// ErrorDetails 错误实现该接口时, Details的返回值以JSON编码在响应的Details字段中返回给调用方.
+type ErrorDetails interface {
+	Details() interface{}
+}
+
+func errorDetails(err error) json.RawMessage {
+	var d ErrorDetails
+	if !errors.As(err, &d) {
+		return nil
+	}
+	data, err := json.Marshal(d.Details())
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// ErrorInfo 客户端收到的错误响应
+type ErrorInfo struct {
+	Code    codes.Code
+	Cause   string
+	Stack   string
+	Details json.RawMessage
+}
+
+// DecodeDetails 将Details解码到v
+func (i *ErrorInfo) DecodeDetails(v interface{}) error {
+	if len(i.Details) <= 0 {
+		return nil
+	}
+	return json.Unmarshal(i.Details, v)
+}
+
+// ErrorDecoder 由错误响应构造错误, 返回nil时使用*Error.
+type ErrorDecoder func(info *ErrorInfo) error
+
+var errorDecoders sync.Map
+
+// RegisterError 注册code对应的错误类型, Client.Call收到该code的错误响应时返回dec构造的错误,
+// 调用方可以使用errors.As获取具体类型.
+func RegisterError(code codes.Code, dec ErrorDecoder) {
+	errorDecoders.Store(code, dec)
+}
+
+func decodeError(er *errReply) error {
+	code := codes.Code(er.Code)
+	if v, ok := errorDecoders.Load(code); ok {
+		info := &ErrorInfo{Code: code, Cause: er.Cause, Stack: er.Stack, Details: er.Details}
+		if err := v.(ErrorDecoder)(info); err != nil {
+			return err
+		}
+	}
+	return clientError(code, er.Cause, er.Stack)
+}
@@ -0,0 +1,82 @@
+package httprpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+const codeNotFound codes.Code = -10101
+
+func init() {
+	codes.Register(codeNotFound, "not found", http.StatusNotFound)
+}
+
+type NotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Kind + " " + e.Name + " not found"
+}
+
+func (e *NotFoundError) Code() codes.Code {
+	return codeNotFound
+}
+
+func (e *NotFoundError) Details() interface{} {
+	return e
+}
+
+type Devices struct{}
+
+func (Devices) Get(ctx context.Context, name string, reply *Reply) error {
+	switch name {
+	case "missing":
+		return &NotFoundError{Kind: "device", Name: name}
+	case "invalid":
+		return Errorf(codes.InvalidHeader, "invalid device %s", name)
+	}
+	return nil
+}
+
+func TestClientTypedError(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/devices", Devices{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+
+	RegisterError(codeNotFound, func(info *ErrorInfo) error {
+		var e NotFoundError
+		if err := info.DecodeDetails(&e); err != nil {
+			return nil
+		}
+		return &e
+	})
+
+	c := NewClient(svr.URL, nil)
+	err := c.Call(context.Background(), "/devices/Get", "missing", &Reply{})
+	var nf *NotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("Call: got %T %v, want *NotFoundError", err, err)
+	}
+	if nf.Kind != "device" || nf.Name != "missing" {
+		t.Errorf("NotFoundError: got %+v", nf)
+	}
+	if got := GetErrorCode(err); got != codeNotFound {
+		t.Errorf("code: got %v, want %v", got, codeNotFound)
+	}
+
+	// 未注册的code返回*Error
+	err = c.Call(context.Background(), "/devices/Get", "invalid", &Reply{})
+	if _, ok := err.(*Error); !ok || GetErrorCode(err) != codes.InvalidHeader {
+		t.Errorf("Call: got %T %v, want *Error", err, err)
+	}
+}
@@ -5,16 +5,24 @@ import (
 	"net/http"
 
 	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/contract"
 	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/examples/arith"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/openapi"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/playground"
 )
 
 func main() {
-	//httprpc.StackTrace = true
-
 	var a arith.Arith
 	s := httprpc.NewServer(nil)
+	//s.SetStackPolicy(httprpc.DevelopmentStack)
 	if err := s.Register("/arith/v0", &a); err != nil {
 		log.Fatalf("register: %v", err)
 	}
-	http.ListenAndServe(":8000", s)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", s)
+	mux.Handle("/openapi.json", openapi.Handler(s, openapi.Options{Info: openapi.Info{Title: "arith", Version: "v0"}}))
+	mux.Handle("/contract.json", contract.Handler(s, contract.Options{}))
+	mux.Handle("/playground/", playground.Handler(s, playground.Options{Title: "arith"}))
+	http.ListenAndServe(":8000", mux)
 }
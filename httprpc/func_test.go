@@ -0,0 +1,146 @@
+package httprpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type Clock struct{}
+
+func (Clock) Now(ctx context.Context, reply *int64) error {
+	*reply = 42
+	return nil
+}
+
+func (Clock) Add(ctx context.Context, args *Args) (*Reply, error) {
+	return &Reply{C: args.A + args.B}, nil
+}
+
+func (Clock) Zone(ctx context.Context) (string, error) {
+	return "UTC", nil
+}
+
+func TestServeHTTPShapes(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/clock", Clock{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.RegisterFunc("/funcs/Sub", func(ctx context.Context, args Args) (Reply, error) {
+		return Reply{C: args.A - args.B}, nil
+	}); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+	if err := s.RegisterFunc("/funcs/Fail", func(ctx context.Context, args Args, reply *Reply) error {
+		return NewError(codes.Conflict, errors.New("conflict"))
+	}); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+
+	var now int64
+	var zone string
+	tests := []struct {
+		path   string
+		args   interface{}
+		result interface{}
+		reply  interface{}
+	}{
+		{path: "/clock/Now", args: nil, result: int64(42), reply: &now},
+		{path: "/clock/Add", args: Args{A: 1, B: 2}, result: Reply{C: 3}, reply: &Reply{}},
+		{path: "/clock/Zone", args: nil, result: "UTC", reply: &zone},
+		{path: "/funcs/Sub", args: Args{A: 3, B: 1}, result: Reply{C: 2}, reply: &Reply{}},
+	}
+	for _, tt := range tests {
+		if err := callTestServer(s, tt.path, tt.args, tt.reply); err != nil {
+			t.Fatalf("callTestServer(%s): %v", tt.path, err)
+		}
+		if got, want := reflect.ValueOf(tt.reply).Elem().Interface(), tt.result; !reflect.DeepEqual(got, want) {
+			t.Fatalf("callTestServer(%s): reply: got %v, want %v", tt.path, got, want)
+		}
+	}
+
+	err := callTestServer(s, "/funcs/Fail", Args{}, &Reply{})
+	if got, want := GetErrorCode(err), codes.Conflict; got != want {
+		t.Fatalf("callTestServer(/funcs/Fail): code: got %v, want %v", got, want)
+	}
+
+	paths := make(map[string]bool)
+	for _, m := range s.Methods() {
+		paths[m.Path] = true
+	}
+	for _, path := range []string{"/clock/Now", "/clock/Add", "/clock/Zone", "/funcs/Sub", "/funcs/Fail"} {
+		if !paths[path] {
+			t.Fatalf("Methods: %s not found", path)
+		}
+	}
+}
+
+func TestRegisterFuncError(t *testing.T) {
+	s := NewServer(nil)
+	var a Arith
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	ok := func(ctx context.Context, args int) (int, error) { return args, nil }
+	if err := s.RegisterFunc("/funcs/Echo", ok); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		fn   interface{}
+		err  string
+	}{
+		{path: "/funcs/Echo", fn: ok, err: "function already defined"},
+		{path: "/arith/Echo", fn: ok, err: "class already defined"},
+		{path: "/funcs/Nil", fn: nil, err: "not a non-nil func"},
+		{path: "/funcs/Int", fn: 1, err: "not a non-nil func"},
+		{path: "/funcs/Ins", fn: func(int, int, *int) error { return nil }, err: "context type not implements context.Context"},
+		{path: "/funcs/Outs", fn: func(context.Context, int) int { return 0 }, err: "returns int not error"},
+		{path: "/funcs/Many", fn: func(context.Context, int, int, *int) error { return nil }, err: "wrong number of ins: 4"},
+	}
+	for _, tt := range tests {
+		err := s.RegisterFunc(tt.path, tt.fn)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("RegisterFunc(%s): got %v, want %q", tt.path, err, tt.err)
+		}
+		t.Logf("RegisterFunc(%s): %v", tt.path, err)
+	}
+	if err := s.Register("/funcs", &a); err == nil {
+		t.Fatalf("Register(/funcs): return nil error")
+	}
+}
+
+func TestRegisterFuncWhileServing(t *testing.T) {
+	s := NewServer(nil)
+	sub := func(ctx context.Context, args Args) (Reply, error) { return Reply{C: args.A - args.B}, nil }
+	if err := s.RegisterFunc("/funcs/Sub", sub); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			if err := s.RegisterFunc(fmt.Sprintf("/funcs/Sub%d", i), sub); err != nil {
+				t.Errorf("RegisterFunc: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		var reply Reply
+		if err := callTestServer(s, "/funcs/Sub", Args{A: 3, B: 1}, &reply); err != nil {
+			t.Fatalf("callTestServer: %v", err)
+		}
+		s.Methods()
+	}
+	<-done
+	if got, want := len(s.Methods()), 51; got != want {
+		t.Fatalf("Methods: got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package httprpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Hedging 对冲请求配置.
+//
+// 调用Methods中的幂等方法时, 若请求在延迟时间内未完成, 向Endpoints中的下一个地址发送相同请求,
+// 使用最先成功的响应并取消另一个请求, 两个请求都失败时返回服务端的错误. Endpoints不能为空,
+// 通常为Client地址之外的其它副本. Percentile大于0时使用最近调用延迟的该百分位作为延迟,
+// 样本不足时使用Delay. 对冲请求数不超过调用数的MaxRatio, MaxRatio为0时默认为0.1.
+type Hedging struct {
+	Methods    []string
+	Endpoints  []string
+	Delay      time.Duration
+	Percentile float64
+	MaxRatio   float64
+}
+
+// HedgingStats 对冲统计.
+//
+// Calls为可对冲方法的调用数, Hedged为发出的对冲请求数, Won为使用了对冲请求成功响应的次数,
+// Throttled为因超过MaxRatio而未发出对冲请求的次数.
+type HedgingStats struct {
+	Calls     uint64
+	Hedged    uint64
+	Won       uint64
+	Throttled uint64
+}
+
+const (
+	hedgingSamples    = 1000
+	hedgingMinSamples = 100
+	hedgingRecompute  = 100
+)
+
+type hedging struct {
+	Hedging
+	next  uint32
+	stats HedgingStats
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	pos       int
+	count     int
+	delay     time.Duration
+}
+
+// SetHedging 开启对冲请求, h为nil时关闭, 需在开始调用前设置.
+func (c *Client) SetHedging(h *Hedging) error {
+	if h == nil {
+		c.hedging = nil
+		return nil
+	}
+	if len(h.Endpoints) <= 0 {
+		return errors.New("hedging: endpoints must be set")
+	}
+	if h.Delay <= 0 && h.Percentile <= 0 {
+		return errors.New("hedging: delay or percentile must be set")
+	}
+	if h.Percentile < 0 || h.Percentile >= 100 {
+		return errors.New("hedging: percentile must be in [0, 100)")
+	}
+	if h.MaxRatio < 0 || h.MaxRatio > 1 {
+		return errors.New("hedging: max ratio must be in [0, 1]")
+	}
+	hg := &hedging{Hedging: *h, delay: h.Delay}
+	if hg.MaxRatio == 0 {
+		hg.MaxRatio = 0.1
+	}
+	c.hedging = hg
+	return nil
+}
+
+func (c *Client) HedgingStats() HedgingStats {
+	h := c.hedging
+	if h == nil {
+		return HedgingStats{}
+	}
+	return HedgingStats{
+		Calls:     atomic.LoadUint64(&h.stats.Calls),
+		Hedged:    atomic.LoadUint64(&h.stats.Hedged),
+		Won:       atomic.LoadUint64(&h.stats.Won),
+		Throttled: atomic.LoadUint64(&h.stats.Throttled),
+	}
+}
+
+func (h *hedging) match(path string) bool {
+	for _, pattern := range h.Methods {
+		if MatchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *hedging) endpoint() string {
+	n := atomic.AddUint32(&h.next, 1)
+	return h.Endpoints[int(n-1)%len(h.Endpoints)]
+}
+
+// acquire 检查对冲请求是否超过上限
+func (h *hedging) acquire() bool {
+	calls := atomic.LoadUint64(&h.stats.Calls)
+	if float64(atomic.LoadUint64(&h.stats.Hedged)+1) > h.MaxRatio*float64(calls) {
+		atomic.AddUint64(&h.stats.Throttled, 1)
+		return false
+	}
+	atomic.AddUint64(&h.stats.Hedged, 1)
+	return true
+}
+
+func (h *hedging) hedgeDelay() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.delay
+}
+
+// observe 记录调用延迟, 每hedgingRecompute个样本重新计算一次百分位延迟.
+func (h *hedging) observe(d time.Duration) {
+	if h.Percentile <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.latencies == nil {
+		h.latencies = make([]time.Duration, hedgingSamples)
+	}
+	h.latencies[h.pos] = d
+	h.pos = (h.pos + 1) % len(h.latencies)
+	h.count++
+	if h.count < hedgingMinSamples || h.count%hedgingRecompute != 0 {
+		return
+	}
+	n := h.count
+	if n > len(h.latencies) {
+		n = len(h.latencies)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, h.latencies[:n])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	h.delay = sorted[int(float64(n-1)*h.Percentile/100)]
+}
+
+type hedgeResult struct {
+	data    []byte
+	err     error
+	replied bool // 已得到服务端的响应, 包括服务端返回的错误
+	hedge   bool
+}
+
+func (c *Client) hedgedCall(ctx context.Context, h *hedging, path string, body []byte) ([]byte, error) {
+	atomic.AddUint64(&h.stats.Calls, 1)
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	results := make(chan hedgeResult, 2)
+	send := func(base string, hedge bool) {
+		data, replied, err := c.roundTrip(ctx, reqCtx, base, path, body, hedge)
+		results <- hedgeResult{data: data, err: err, replied: replied, hedge: hedge}
+	}
+	go send(c.url, false)
+
+	timer := time.NewTimer(h.hedgeDelay())
+	defer timer.Stop()
+	pending := 1
+	var failed *hedgeResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if h.acquire() {
+				pending++
+				go send(h.endpoint(), true)
+			}
+		case r := <-results:
+			pending--
+			if r.replied {
+				h.observe(time.Since(start))
+			}
+			if r.err == nil {
+				if r.hedge {
+					atomic.AddUint64(&h.stats.Won, 1)
+				}
+				return r.data, nil
+			}
+			// 失败时等待另一个请求, 都失败时优先返回服务端的错误
+			if failed == nil || (r.replied && !failed.replied) {
+				failed = &r
+			}
+			if pending <= 0 {
+				timer.Stop()
+			}
+		}
+	}
+	return failed.data, failed.err
+}
@@ -0,0 +1,137 @@
+package httprpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+func newHedgeTestServer(t *testing.T, delay time.Duration) *httptest.Server {
+	var a Arith
+	s := NewServer(nil)
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+}
+
+func TestHedging(t *testing.T) {
+	slow := newHedgeTestServer(t, 200*time.Millisecond)
+	defer slow.Close()
+	fast := newHedgeTestServer(t, 0)
+	defer fast.Close()
+
+	c := NewClient(slow.URL, nil)
+	if err := c.SetHedging(&Hedging{Delay: -1}); err == nil {
+		t.Fatalf("SetHedging return error is nil")
+	}
+	err := c.SetHedging(&Hedging{
+		Methods:   []string{"/arith/Add"},
+		Endpoints: []string{fast.URL},
+		Delay:     20 * time.Millisecond,
+		MaxRatio:  0.5,
+	})
+	if err != nil {
+		t.Fatalf("SetHedging: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		var reply Reply
+		start := time.Now()
+		if err := c.Call(context.Background(), "/arith/Add", Args{A: 1, B: 2}, &reply); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if reply.C != 3 {
+			t.Fatalf("call %d: reply: got %v, want 3", i, reply.C)
+		}
+		t.Logf("call %d: %v", i, time.Since(start))
+	}
+	want := HedgingStats{Calls: 4, Hedged: 2, Won: 2, Throttled: 2}
+	if got := c.HedgingStats(); got != want {
+		t.Errorf("stats: got %+v, want %+v", got, want)
+	}
+
+	// 非幂等方法不对冲
+	var reply Reply
+	if err := c.Call(context.Background(), "/arith/Mul", Args{A: 1, B: 2}, &reply); err != nil {
+		t.Fatalf("call Mul: %v", err)
+	}
+	if got := c.HedgingStats(); got != want {
+		t.Errorf("stats after Mul: got %+v, want %+v", got, want)
+	}
+}
+
+func TestHedgingPercentile(t *testing.T) {
+	h := hedging{Hedging: Hedging{Percentile: 90}, delay: time.Second}
+	for i := 1; i <= hedgingMinSamples; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+	if got, want := h.hedgeDelay(), 90*time.Millisecond; got != want {
+		t.Errorf("delay: got %v, want %v", got, want)
+	}
+}
+
+type busyError struct{ cause string }
+
+func (e *busyError) Error() string { return e.cause }
+
+func TestHedgingError(t *testing.T) {
+	const codeBusy = codes.Code(-1901)
+	RegisterError(codeBusy, func(info *ErrorInfo) error { return &busyError{cause: info.Cause} })
+
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"Code":-1901,"Error":"busy","Cause":"device busy"}`))
+	}))
+	defer primary.Close()
+	hedge := newHedgeTestServer(t, 200*time.Millisecond)
+	defer hedge.Close()
+	closed := newHedgeTestServer(t, 0)
+	closed.Close()
+
+	c := NewClient(primary.URL, nil)
+	if err := c.SetHedging(&Hedging{Methods: []string{"/arith/Add"}, Delay: time.Millisecond}); err == nil {
+		t.Fatalf("SetHedging without endpoints: return error is nil")
+	}
+	err := c.SetHedging(&Hedging{
+		Methods:   []string{"/arith/Add"},
+		Endpoints: []string{hedge.URL, closed.URL},
+		Delay:     10 * time.Millisecond,
+		MaxRatio:  1,
+	})
+	if err != nil {
+		t.Fatalf("SetHedging: %v", err)
+	}
+
+	// 服务端返回错误时等待对冲请求的成功响应
+	var reply Reply
+	if err = c.Call(context.Background(), "/arith/Add", Args{A: 1, B: 2}, &reply); err != nil || reply.C != 3 {
+		t.Fatalf("call: reply %v, err %v", reply, err)
+	}
+	if got := c.HedgingStats(); got.Hedged != 1 || got.Won != 1 {
+		t.Errorf("stats: %+v", got)
+	}
+
+	// 都失败时返回服务端的错误, 对冲请求不计为Won
+	err = c.Call(context.Background(), "/arith/Add", Args{A: 1, B: 2}, &Reply{})
+	var busy *busyError
+	if !errors.As(err, &busy) {
+		t.Fatalf("call: got %v, want *busyError", err)
+	}
+	if got := c.HedgingStats(); got.Hedged != 2 || got.Won != 1 {
+		t.Errorf("stats: %+v", got)
+	}
+}
@@ -0,0 +1,265 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+const (
+	xIdempotencyKey    = "X-Idempotency-Key"
+	xIdempotentReplay  = "X-Idempotent-Replay"
+	defaultIdempotency = 24 * time.Hour
+)
+
+// IdempotencyRecord 第一次执行的结果, Code不为0时为错误结果.
+type IdempotencyRecord struct {
+	ArgsHash string
+	Status   int
+	Header   http.Header     `json:",omitempty"`
+	Body     []byte          `json:",omitempty"`
+	Code     int             `json:",omitempty"`
+	Cause    string          `json:",omitempty"`
+	Details  json.RawMessage `json:",omitempty"`
+}
+
+// IdempotencyStore 幂等结果存储.
+//
+// Reserve占用key: 占用成功时返回reserved为true; key已有结果时返回该结果;
+// 其它调用正在执行时返回nil和false. 占用和结果都在ttl后过期.
+type IdempotencyStore interface {
+	Reserve(key string, ttl time.Duration) (rec *IdempotencyRecord, reserved bool, err error)
+	Save(key string, rec *IdempotencyRecord, ttl time.Duration) error
+	Release(key string) error
+}
+
+type memoryEntry struct {
+	rec     *IdempotencyRecord
+	expires time.Time
+}
+
+// MemoryIdempotencyStore 进程内的IdempotencyStore
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: make(map[string]memoryEntry)}
+}
+
+func (m *MemoryIdempotencyStore) Reserve(key string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.sweep(now, ttl)
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		return e.rec, false, nil
+	}
+	m.entries[key] = memoryEntry{expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (m *MemoryIdempotencyStore) Save(key string, rec *IdempotencyRecord, ttl time.Duration) error {
+	m.mu.Lock()
+	m.entries[key] = memoryEntry{rec: rec, expires: time.Now().Add(ttl)}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *MemoryIdempotencyStore) Release(key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+// sweep 每隔一个ttl清理一次过期的项
+func (m *MemoryIdempotencyStore) sweep(now time.Time, ttl time.Duration) {
+	if now.Sub(m.lastSweep) < ttl {
+		return
+	}
+	m.lastSweep = now
+	for key, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// IdempotencyOptions 幂等选项.
+//
+// Paths为空时对所有方法生效, 否则只对MatchPath匹配的方法生效; TTL为0时默认24小时.
+// 相同key的调用正在执行时, Wait大于0则最多等待Wait时间, 否则直接返回codes.Conflict.
+type IdempotencyOptions struct {
+	Paths []string
+	TTL   time.Duration
+	Wait  time.Duration
+}
+
+// Idempotency 幂等中间件.
+//
+// 请求携带X-Idempotency-Key头时, 按client id、路径和key保存第一次执行的结果,
+// 之后相同的请求直接返回该结果; 参数不同的请求返回codes.Conflict.
+// 上传文件和流式读写的方法不保存结果.
+type Idempotency struct {
+	store   IdempotencyStore
+	options IdempotencyOptions
+}
+
+// NewIdempotency 创建幂等中间件, store为nil时使用MemoryIdempotencyStore.
+func NewIdempotency(store IdempotencyStore, o IdempotencyOptions) *Idempotency {
+	if store == nil {
+		store = NewMemoryIdempotencyStore()
+	}
+	if o.TTL <= 0 {
+		o.TTL = defaultIdempotency
+	}
+	return &Idempotency{store: store, options: o}
+}
+
+func (p *Idempotency) match(path string) bool {
+	if len(p.options.Paths) <= 0 {
+		return true
+	}
+	for _, pattern := range p.options.Paths {
+		if MatchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Idempotency) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, next NextMiddleware) error {
+	ikey := r.Header.Get(xIdempotencyKey)
+	path := normalizePath(r.URL.Path)
+	if ikey == "" || !p.match(path) {
+		return next(ctx, w, r)
+	}
+	if rctx, ok := ctx.(*Context); ok && !recordable(rctx.method, r) {
+		return next(ctx, w, r)
+	}
+
+	args, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return NewError(codes.DecodeBodyFail, err)
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(args))
+	sum := sha256.Sum256(args)
+	argsHash := hex.EncodeToString(sum[:])
+	key := r.Header.Get(xClientID) + "|" + path + "|" + ikey
+
+	rec, err := p.reserve(key)
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		if rec.ArgsHash != argsHash {
+			return Errorf(codes.Conflict, "idempotency key %s is used with different args", ikey)
+		}
+		return p.replay(ctx, w, rec)
+	}
+
+	c := newResponseCapture(w)
+	completed := false
+	defer func() {
+		if !completed {
+			p.store.Release(key)
+		}
+	}()
+	err = next(ctx, c, r)
+	if GetErrorCode(err) == codes.Unavailable {
+		// 方法未执行, 允许重试
+		return err
+	}
+	rec = &IdempotencyRecord{ArgsHash: argsHash}
+	if err != nil {
+		code, er := newErrReply(err, false)
+		rec.Code, rec.Cause, rec.Details, rec.Status = er.Code, er.Cause, er.Details, code.Status()
+	} else {
+		rec.Status = c.status
+		rec.Header = cachedHeader(ctx, w.Header())
+		rec.Header.Del(xTraceID)
+		rec.Body = c.body.Bytes()
+	}
+	if serr := p.store.Save(key, rec, p.options.TTL); serr != nil {
+		Logger().WithContext(ctx).Warnw("save idempotency record", "key", key, "error", serr)
+	} else {
+		completed = true
+	}
+	return err
+}
+
+// reserve 占用key, 相同key的调用正在执行时等待或返回codes.Conflict.
+func (p *Idempotency) reserve(key string) (*IdempotencyRecord, error) {
+	deadline := time.Now().Add(p.options.Wait)
+	interval := p.options.Wait / 20
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	for {
+		rec, reserved, err := p.store.Reserve(key, p.options.TTL)
+		if err != nil {
+			return nil, NewError(codes.Unavailable, err)
+		}
+		if reserved || rec != nil {
+			return rec, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, Errorf(codes.Conflict, "a request with the same idempotency key is in progress")
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (p *Idempotency) replay(ctx context.Context, w http.ResponseWriter, rec *IdempotencyRecord) error {
+	w.Header().Set(xIdempotentReplay, "true")
+	if rec.Code != 0 {
+		err := clientError(codes.Code(rec.Code), rec.Cause, "")
+		if len(rec.Details) > 0 {
+			return &replayError{err: err.(*Error), details: rec.Details}
+		}
+		return err
+	}
+	h := w.Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	if rctx, ok := ctx.(*Context); ok {
+		setHeaderTraceID(h, rctx.TraceID)
+	}
+	w.WriteHeader(rec.Status)
+	w.Write(rec.Body)
+	return nil
+}
+
+// replayError 重放的错误结果, 保留第一次执行时的Details
+type replayError struct {
+	err     *Error
+	details json.RawMessage
+}
+
+func (e *replayError) Error() string {
+	return e.err.Error()
+}
+
+func (e *replayError) Code() codes.Code {
+	return e.err.Code()
+}
+
+func (e *replayError) Cause() error {
+	return e.err.Cause()
+}
+
+func (e *replayError) Details() interface{} {
+	return e.details
+}
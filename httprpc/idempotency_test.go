@@ -0,0 +1,167 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type Payment struct {
+	calls int32
+	delay time.Duration
+}
+
+func (p *Payment) Pay(ctx context.Context, args *Args, reply *Reply) error {
+	time.Sleep(p.delay)
+	reply.C = int(atomic.AddInt32(&p.calls, 1))
+	if args.A < 0 {
+		return Errorf(-1, "invalid amount")
+	}
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	var p Payment
+	s := NewServer(nil)
+	if err := s.Register("/payment", &p); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	s.AddMiddleware(NewIdempotency(nil, IdempotencyOptions{}))
+
+	call := func(client, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/payment/Pay", strings.NewReader(body))
+		r.Header.Set(xClientID, client)
+		if key != "" {
+			r.Header.Set(xIdempotencyKey, key)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		client string
+		key    string
+		body   string
+		status int
+		reply  string
+		replay bool
+	}{
+		{client: "c1", key: "k1", body: `{"A":1}`, status: http.StatusOK, reply: `{"C":1}`},
+		{client: "c1", key: "k1", body: `{"A":1}`, status: http.StatusOK, reply: `{"C":1}`, replay: true},
+		{client: "c1", key: "k1", body: `{"A":2}`, status: http.StatusConflict},
+		{client: "c2", key: "k1", body: `{"A":1}`, status: http.StatusOK, reply: `{"C":2}`},
+		{client: "c1", key: "", body: `{"A":1}`, status: http.StatusOK, reply: `{"C":3}`},
+		{client: "c1", key: "k2", body: `{"A":-1}`, status: http.StatusInternalServerError},
+		{client: "c1", key: "k2", body: `{"A":-1}`, status: http.StatusInternalServerError, replay: true},
+	}
+	for i, tt := range tests {
+		w := call(tt.client, tt.key, tt.body)
+		if w.Code != tt.status {
+			t.Errorf("case%d: status: got %d, want %d, body: %s", i, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.reply != "" && strings.TrimSpace(w.Body.String()) != tt.reply {
+			t.Errorf("case%d: reply: got %s, want %s", i, w.Body, tt.reply)
+		}
+		if got := w.Header().Get(xIdempotentReplay) != ""; got != tt.replay {
+			t.Errorf("case%d: replay: got %v, want %v", i, got, tt.replay)
+		}
+	}
+	if got := atomic.LoadInt32(&p.calls); got != 4 {
+		t.Errorf("calls: got %d, want 4", got)
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var p Payment
+	s := NewServer(nil)
+	for name, rcvr := range map[string]interface{}{"/payment": &p, "/devices": Devices{}, "/firmware": Firmware{}} {
+		if err := s.Register(name, rcvr); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	s.SetCORS(NewCORS("https://a.example.com", "https://b.example.com"))
+	s.AddMiddleware(NewIdempotency(nil, IdempotencyOptions{}))
+
+	call := func(origin, path, contentType, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set("Origin", origin)
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set(xIdempotencyKey, "k1")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	// 重放时CORS头按当前请求设置
+	call("https://a.example.com", "/payment/Pay", "application/json", `{"A":1}`)
+	w := call("https://b.example.com", "/payment/Pay", "application/json", `{"A":1}`)
+	if w.Header().Get(xIdempotentReplay) == "" {
+		t.Fatalf("Pay: not replayed")
+	}
+	if got, want := w.Header().Get("Access-Control-Allow-Origin"), "https://b.example.com"; got != want {
+		t.Errorf("Access-Control-Allow-Origin: got %v, want %v", got, want)
+	}
+
+	// 错误重放保留Details
+	call("https://a.example.com", "/devices/Get", "application/json", `"missing"`)
+	w = call("https://a.example.com", "/devices/Get", "application/json", `"missing"`)
+	if w.Header().Get(xIdempotentReplay) == "" {
+		t.Fatalf("Get: not replayed")
+	}
+	if !strings.Contains(w.Body.String(), `"Details":{"Kind":"device","Name":"missing"}`) {
+		t.Errorf("Get: details not replayed: %s", w.Body)
+	}
+
+	// 流式方法不保存结果
+	call("https://a.example.com", "/firmware/Upload", "application/octet-stream", "image")
+	w = call("https://a.example.com", "/firmware/Upload", "application/octet-stream", "image")
+	if w.Header().Get(xIdempotentReplay) != "" {
+		t.Errorf("Upload: replayed")
+	}
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	for _, wait := range []time.Duration{0, time.Second} {
+		p := Payment{delay: 100 * time.Millisecond}
+		s := NewServer(nil)
+		if err := s.Register("/payment", &p); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		s.AddMiddleware(NewIdempotency(nil, IdempotencyOptions{Wait: wait}))
+
+		var wg sync.WaitGroup
+		status := make([]int, 2)
+		for i := range status {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				time.Sleep(time.Duration(i) * 20 * time.Millisecond)
+				r := httptest.NewRequest("POST", "/payment/Pay", bytes.NewReader([]byte(`{"A":1}`)))
+				r.Header.Set(xIdempotencyKey, "k")
+				w := httptest.NewRecorder()
+				s.ServeHTTP(w, r)
+				status[i] = w.Code
+			}(i)
+		}
+		wg.Wait()
+
+		want := []int{http.StatusOK, http.StatusConflict}
+		if wait > 0 {
+			want[1] = http.StatusOK
+		}
+		if status[0] != want[0] || status[1] != want[1] {
+			t.Errorf("wait=%v: status: got %v, want %v", wait, status, want)
+		}
+		if got := atomic.LoadInt32(&p.calls); got != 1 {
+			t.Errorf("wait=%v: calls: got %d, want 1", wait, got)
+		}
+	}
+}
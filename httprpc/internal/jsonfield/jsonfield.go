@@ -0,0 +1,87 @@
+package jsonfield
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field 结构体经JSON编码后的字段
+type Field struct {
+	Name      string
+	Type      reflect.Type
+	OmitEmpty bool
+	Index     []int
+}
+
+// Fields 按encoding/json的规则返回结构体的字段, 匿名结构体字段展开到外层.
+func Fields(t reflect.Type) []Field {
+	var fields []Field
+	seen := make(map[string]bool)
+	collectFields(t, nil, &fields, seen)
+	return fields
+}
+
+func collectFields(t reflect.Type, index []int, fields *[]Field, seen map[string]bool) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.Index(tag, ","); j >= 0 {
+			name, opts = tag[:j], tag[j+1:]
+		}
+		ft := sf.Type
+		if sf.Anonymous && name == "" {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, sf)
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		*fields = append(*fields, Field{
+			Name:      name,
+			Type:      sf.Type,
+			OmitEmpty: strings.Contains(opts, "omitempty"),
+			Index:     append(index[:len(index):len(index)], i),
+		})
+	}
+	// 外层字段优先于匿名结构体中的同名字段
+	for _, sf := range embedded {
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		collectFields(ft, append(index[:len(index):len(index)], sf.Index[0]), fields, seen)
+	}
+}
+
+// Value 返回结构体v中该字段的值, 路径上为nil的匿名结构体指针会被分配.
+func (f Field) Value(v reflect.Value) reflect.Value {
+	for i, x := range f.Index {
+		if i > 0 {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+package httprpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// invoker 调用已绑定接收者的方法
+type invoker func(ctx context.Context, args, reply reflect.Value) error
+
+// newInvoker 在注册时为方法生成invoker.
+// 只有args和reply为interface{}或string的签名通过类型断言直接调用; 结构体等其它类型的签名,
+// 包括最常用的func(ctx, *Args, *Reply) error, 仍使用反射调用, 只省去了每次分配参数切片.
+func newInvoker(fn reflect.Value, m *method) invoker {
+	if m.noArgs || m.returns {
+		return newShapeInvoker(fn, m.noArgs, m.returns)
+	}
+	switch f := fn.Interface().(type) {
+	case func(context.Context, interface{}, interface{}) error:
+		return func(ctx context.Context, args, reply reflect.Value) error {
+			return f(ctx, args.Interface(), reply.Interface())
+		}
+	case func(*Context, interface{}, interface{}) error:
+		return func(ctx context.Context, args, reply reflect.Value) error {
+			return f(ctx.(*Context), args.Interface(), reply.Interface())
+		}
+	case func(context.Context, string, *string) error:
+		return func(ctx context.Context, args, reply reflect.Value) error {
+			return f(ctx, args.String(), reply.Interface().(*string))
+		}
+	case func(*Context, string, *string) error:
+		return func(ctx context.Context, args, reply reflect.Value) error {
+			return f(ctx.(*Context), args.String(), reply.Interface().(*string))
+		}
+	}
+
+	pool := sync.Pool{New: func() interface{} { return new([3]reflect.Value) }}
+	return func(ctx context.Context, args, reply reflect.Value) error {
+		in := pool.Get().(*[3]reflect.Value)
+		in[0], in[1], in[2] = reflect.ValueOf(ctx), args, reply
+		rets := fn.Call(in[:])
+		*in = [3]reflect.Value{}
+		pool.Put(in)
+		return toError(rets[0])
+	}
+}
+
+// newShapeInvoker 调用没有args或以返回值返回reply的方法, 返回的reply保存到reply指向的值中.
+func newShapeInvoker(fn reflect.Value, noArgs, returns bool) invoker {
+	return func(ctx context.Context, args, reply reflect.Value) error {
+		in := make([]reflect.Value, 1, 3)
+		in[0] = reflect.ValueOf(ctx)
+		if !noArgs {
+			in = append(in, args)
+		}
+		if !returns {
+			in = append(in, reply)
+		}
+		rets := fn.Call(in)
+		if returns {
+			reply.Elem().Set(rets[0])
+			rets = rets[1:]
+		}
+		return toError(rets[0])
+	}
+}
+
+func toError(v reflect.Value) error {
+	if v.IsNil() {
+		return nil
+	}
+	return v.Interface().(error)
+}
+
+// isFlat 类型的值不包含指针、map、slice等引用, 零值化后复用不会泄漏上次请求的数据.
+func isFlat(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return isFlat(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !isFlat(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// callValues 一次调用的args和reply, argsPtr为args实际存储的指针.
+type callValues struct {
+	argsPtr reflect.Value
+	args    reflect.Value
+	reply   reflect.Value
+}
+
+// alloc 分配args和reply.
+//
+// 以值传递且不含引用的args总是从池中复用, 方法无法持有其存储;
+// 指针args和reply只在pooling为true时复用, 此时方法返回后不能再持有它们.
+func (m *method) alloc(pooling bool) (v callValues) {
+	if pooling || m.flatArgs {
+		if x := m.argsPool.Get(); x != nil {
+			v.argsPtr = reflect.ValueOf(x)
+		}
+	}
+	if !v.argsPtr.IsValid() {
+		if m.args.Kind() == reflect.Ptr {
+			v.argsPtr = reflect.New(m.args.Elem())
+		} else {
+			v.argsPtr = reflect.New(m.args)
+		}
+	}
+	if m.args.Kind() == reflect.Ptr {
+		v.args = v.argsPtr
+	} else {
+		v.args = v.argsPtr.Elem()
+	}
+
+	if pooling {
+		if x := m.replyPool.Get(); x != nil {
+			v.reply = reflect.ValueOf(x)
+			initReply(m.reply, v.reply)
+		}
+	}
+	if !v.reply.IsValid() {
+		v.reply = newReply(m.reply)
+	}
+	return v
+}
+
+// free 将args和reply零值化后放回池中
+func (m *method) free(v callValues, pooling bool) {
+	if pooling || m.flatArgs {
+		v.argsPtr.Elem().Set(reflect.Zero(v.argsPtr.Type().Elem()))
+		m.argsPool.Put(v.argsPtr.Interface())
+	}
+	if pooling {
+		v.reply.Elem().Set(reflect.Zero(v.reply.Type().Elem()))
+		m.replyPool.Put(v.reply.Interface())
+	}
+}
@@ -0,0 +1,177 @@
+package httprpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type Bench struct{}
+
+func (Bench) Struct(ctx context.Context, args Args, reply *Reply) error {
+	reply.C = args.A + args.B
+	return nil
+}
+
+func (Bench) Pointer(ctx context.Context, args *Args, reply *Reply) error {
+	reply.C = args.A + args.B
+	return nil
+}
+
+func (Bench) String(ctx context.Context, args string, reply *string) error {
+	*reply = args
+	return nil
+}
+
+func (Bench) Interface(ctx context.Context, args interface{}, reply interface{}) error {
+	return nil
+}
+
+func TestInvoker(t *testing.T) {
+	c, err := parseClass("/bench", reflect.ValueOf(Bench{}))
+	if err != nil {
+		t.Fatalf("parse class: %v", err)
+	}
+	ctx := context.Background()
+
+	m := c.methods["Struct"]
+	v := m.alloc(false)
+	v.args.Set(reflect.ValueOf(Args{A: 1, B: 2}))
+	if err = m.invoke(ctx, v.args, v.reply); err != nil {
+		t.Fatalf("invoke Struct: %v", err)
+	}
+	if got, want := v.reply.Interface().(*Reply).C, 3; got != want {
+		t.Errorf("Struct: got %v, want %v", got, want)
+	}
+
+	m = c.methods["String"]
+	v = m.alloc(false)
+	v.args.SetString("hello")
+	if err = m.invoke(ctx, v.args, v.reply); err != nil {
+		t.Fatalf("invoke String: %v", err)
+	}
+	if got, want := *v.reply.Interface().(*string), "hello"; got != want {
+		t.Errorf("String: got %v, want %v", got, want)
+	}
+}
+
+func TestMethodPool(t *testing.T) {
+	c, err := parseClass("/bench", reflect.ValueOf(Bench{}))
+	if err != nil {
+		t.Fatalf("parse class: %v", err)
+	}
+	if !c.methods["Struct"].flatArgs || c.methods["Pointer"].flatArgs || c.methods["Interface"].flatArgs {
+		t.Fatalf("flatArgs: Struct=%v, Pointer=%v, Interface=%v", c.methods["Struct"].flatArgs,
+			c.methods["Pointer"].flatArgs, c.methods["Interface"].flatArgs)
+	}
+
+	for _, pooling := range []bool{false, true} {
+		m := c.methods["Pointer"]
+		v := m.alloc(pooling)
+		v.args.Elem().Set(reflect.ValueOf(Args{A: 1, B: 2}))
+		v.reply.Elem().Set(reflect.ValueOf(Reply{C: 3}))
+		m.free(v, pooling)
+		for i := 0; i < 10; i++ {
+			v = m.alloc(pooling)
+			if got := v.args.Elem().Interface().(Args); got != (Args{}) {
+				t.Fatalf("pooling=%v: args not reset: %v", pooling, got)
+			}
+			if got := v.reply.Elem().Interface().(Reply); got != (Reply{}) {
+				t.Fatalf("pooling=%v: reply not reset: %v", pooling, got)
+			}
+			m.free(v, pooling)
+		}
+	}
+}
+
+// callReflect 预计算invoker之前的调用方式
+func callReflect(ctx context.Context, m *method, rcvr reflect.Value, args interface{}) error {
+	var argv reflect.Value
+	if m.args.Kind() == reflect.Ptr {
+		argv = reflect.New(m.args.Elem())
+		argv.Elem().Set(reflect.ValueOf(args).Elem())
+	} else {
+		argv = reflect.New(m.args).Elem()
+		if args != nil {
+			argv.Set(reflect.ValueOf(args))
+		}
+	}
+	reply := newReply(m.reply)
+	rets := m.method.Func.Call([]reflect.Value{rcvr, reflect.ValueOf(ctx), argv, reply})
+	if erri := rets[0].Interface(); erri != nil {
+		return erri.(error)
+	}
+	return nil
+}
+
+func callInvoker(ctx context.Context, m *method, pooling bool, args interface{}) error {
+	v := m.alloc(pooling)
+	if m.args.Kind() == reflect.Ptr {
+		v.args.Elem().Set(reflect.ValueOf(args).Elem())
+	} else if args != nil {
+		v.args.Set(reflect.ValueOf(args))
+	}
+	err := m.invoke(ctx, v.args, v.reply)
+	m.free(v, pooling)
+	return err
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	rcvr := reflect.ValueOf(Bench{})
+	c, err := parseClass("/bench", rcvr)
+	if err != nil {
+		b.Fatalf("parse class: %v", err)
+	}
+	ctx := context.Background()
+	cases := []struct {
+		name string
+		args interface{}
+	}{
+		{name: "Struct", args: Args{A: 1, B: 2}},
+		{name: "Pointer", args: &Args{A: 1, B: 2}},
+		{name: "String", args: "hello"},
+		{name: "Interface", args: nil},
+	}
+	for _, tc := range cases {
+		m := c.methods[tc.name]
+		b.Run(tc.name+"/reflect", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				callReflect(ctx, m, rcvr, tc.args)
+			}
+		})
+		b.Run(tc.name+"/invoker", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				callInvoker(ctx, m, false, tc.args)
+			}
+		})
+		b.Run(tc.name+"/pooling", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				callInvoker(ctx, m, true, tc.args)
+			}
+		})
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	s := NewServer(nil)
+	if err := s.Register("/bench", Bench{}); err != nil {
+		b.Fatalf("register: %v", err)
+	}
+	body := []byte(`{"A":1,"B":2}`)
+	for _, pooling := range []bool{false, true} {
+		s.SetPooling(pooling)
+		name := "default"
+		if pooling {
+			name = "pooling"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				serveTestHTTP(s, "POST", "/bench/Pointer", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+const xPriority = "X-Priority"
+
+// AdaptiveOptions 自适应并发上限选项, 使用AIMD算法.
+//
+// 调用延迟不超过Target时上限每次增加1/上限, 即每轮约增加1; 超过Target或返回codes.Unavailable时
+// 上限乘以Backoff, 每个Target时间内最多减小一次. 上限在[Min, Max]内变化, Min为0时为1,
+// Max为0时为配置的初始上限, Backoff为0时为0.9.
+type AdaptiveOptions struct {
+	Target  time.Duration
+	Min     int
+	Max     int
+	Backoff float64
+}
+
+// LimitOptions 并发限制选项.
+//
+// Global为全局并发上限, Methods为各方法路径的并发上限, 为0时不限制. 方法路径为注册时的路径,
+// 如/devices/{id}/Reboot, 通过别名的调用计入别名指向的方法.
+// X-Priority请求头为low的请求只能使用上限的LowPriorityRatio, LowPriorityRatio为0时为0.8,
+// 负载升高时低优先级请求先被拒绝.
+type LimitOptions struct {
+	Global           int
+	Methods          map[string]int
+	Adaptive         *AdaptiveOptions
+	LowPriorityRatio float64
+}
+
+// LimitStats 并发限制统计
+type LimitStats struct {
+	Limit    int
+	Inflight int
+	Shed     uint64
+}
+
+type limiter struct {
+	adaptive *AdaptiveOptions
+	min, max float64
+
+	mu           sync.Mutex
+	limit        float64
+	inflight     int
+	shed         uint64
+	lastDecrease time.Time
+}
+
+func newLimiter(limit int, a *AdaptiveOptions) *limiter {
+	l := &limiter{limit: float64(limit), min: float64(limit), max: float64(limit)}
+	if a != nil {
+		l.adaptive = a
+		l.min, l.max = float64(a.Min), float64(a.Max)
+		if l.min <= 0 {
+			l.min = 1
+		}
+		if l.max <= 0 {
+			l.max = float64(limit)
+		}
+	}
+	return l
+}
+
+func (l *limiter) acquire(ratio float64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if float64(l.inflight) >= l.limit*ratio {
+		l.shed++
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// cancel 归还未使用的并发数, 不影响自适应上限
+func (l *limiter) cancel() {
+	l.mu.Lock()
+	l.inflight--
+	l.mu.Unlock()
+}
+
+func (l *limiter) release(latency time.Duration, overload bool, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inflight--
+	a := l.adaptive
+	if a == nil {
+		return
+	}
+	if overload || latency > a.Target {
+		if now.Sub(l.lastDecrease) < a.Target {
+			return
+		}
+		backoff := a.Backoff
+		if backoff <= 0 {
+			backoff = 0.9
+		}
+		l.lastDecrease = now
+		l.limit *= backoff
+	} else {
+		l.limit += 1 / l.limit
+	}
+	if l.limit < l.min {
+		l.limit = l.min
+	} else if l.limit > l.max {
+		l.limit = l.max
+	}
+}
+
+func (l *limiter) stats() LimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LimitStats{Limit: int(l.limit), Inflight: l.inflight, Shed: l.shed}
+}
+
+// Limiter 并发限制中间件, 超过上限的请求返回codes.Unavailable.
+type Limiter struct {
+	lowRatio float64
+	global   *limiter
+	methods  map[string]*limiter
+}
+
+func NewLimiter(o LimitOptions) *Limiter {
+	l := &Limiter{lowRatio: o.LowPriorityRatio, methods: make(map[string]*limiter)}
+	if l.lowRatio <= 0 {
+		l.lowRatio = 0.8
+	}
+	if o.Global > 0 {
+		l.global = newLimiter(o.Global, o.Adaptive)
+	}
+	for path, limit := range o.Methods {
+		if limit > 0 {
+			l.methods[normalizePath(path)] = newLimiter(limit, o.Adaptive)
+		}
+	}
+	return l
+}
+
+// Stats 返回并发限制统计, 全局限制的路径为空字符串.
+func (l *Limiter) Stats() map[string]LimitStats {
+	stats := make(map[string]LimitStats)
+	if l.global != nil {
+		stats[""] = l.global.stats()
+	}
+	for path, m := range l.methods {
+		stats[path] = m.stats()
+	}
+	return stats
+}
+
+func (l *Limiter) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, next NextMiddleware) error {
+	path := methodPath(ctx, r)
+	ratio := 1.0
+	if strings.EqualFold(r.Header.Get(xPriority), "low") {
+		ratio = l.lowRatio
+	}
+
+	var acquired []*limiter
+	for _, lim := range []*limiter{l.methods[path], l.global} {
+		if lim == nil {
+			continue
+		}
+		if !lim.acquire(ratio) {
+			for _, a := range acquired {
+				a.cancel()
+			}
+			w.Header().Set("Retry-After", "1")
+			return Errorf(codes.Unavailable, "too many concurrent calls to %s", path)
+		}
+		acquired = append(acquired, lim)
+	}
+
+	start := time.Now()
+	overload := false
+	defer func() {
+		now := time.Now()
+		for _, a := range acquired {
+			a.release(now.Sub(start), overload, now)
+		}
+	}()
+	err := next(ctx, w, r)
+	overload = GetErrorCode(err) == codes.Unavailable
+	return err
+}
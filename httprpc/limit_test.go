@@ -0,0 +1,194 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Blocker struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *Blocker) Wait(ctx context.Context, args *Args, reply *Reply) error {
+	b.started <- struct{}{}
+	<-b.release
+	return nil
+}
+
+func (b *Blocker) Fast(ctx context.Context, args *Args, reply *Reply) error {
+	return nil
+}
+
+func TestLimiter(t *testing.T) {
+	b := Blocker{started: make(chan struct{}, 10), release: make(chan struct{})}
+	s := NewServer(nil)
+	if err := s.Register("/blocker", &b); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	l := NewLimiter(LimitOptions{Global: 5, Methods: map[string]int{"/blocker/Wait": 2}, LowPriorityRatio: 0.6})
+	s.AddMiddleware(l)
+
+	call := func(path, priority string) int {
+		r := httptest.NewRequest("POST", path, strings.NewReader(`{}`))
+		if priority != "" {
+			r.Header.Set(xPriority, priority)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// 占满/blocker/Wait的上限
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			call("/blocker/Wait", "")
+		}()
+		<-b.started
+	}
+
+	tests := []struct {
+		path     string
+		priority string
+		status   int
+	}{
+		{path: "/blocker/Wait", status: http.StatusServiceUnavailable},
+		{path: "/blocker/Fast", status: http.StatusOK},
+		// 全局并发为2, 低优先级请求上限为5*0.6=3
+		{path: "/blocker/Fast", priority: "low", status: http.StatusOK},
+	}
+	for i, tt := range tests {
+		if got := call(tt.path, tt.priority); got != tt.status {
+			t.Errorf("case%d: status: got %d, want %d", i, got, tt.status)
+		}
+	}
+
+	// 再占用一个全局并发后低优先级请求被拒绝
+	done := make(chan struct{})
+	r := httptest.NewRequest("POST", "/blocker/Fast", strings.NewReader(`{}`))
+	go func() {
+		defer close(done)
+		l.ServeHTTP(context.Background(), httptest.NewRecorder(), r, func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			<-b.release
+			return nil
+		})
+	}()
+	for l.Stats()[""].Inflight != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if got := call("/blocker/Fast", "low"); got != http.StatusServiceUnavailable {
+		t.Errorf("low priority: status: got %d, want %d", got, http.StatusServiceUnavailable)
+	}
+	if got := call("/blocker/Fast", ""); got != http.StatusOK {
+		t.Errorf("normal priority: status: got %d, want %d", got, http.StatusOK)
+	}
+
+	close(b.release)
+	wg.Wait()
+	<-done
+	stats := l.Stats()
+	if stats[""].Inflight != 0 || stats["/blocker/Wait"].Inflight != 0 {
+		t.Errorf("inflight: %+v", stats)
+	}
+	if stats["/blocker/Wait"].Shed != 1 || stats[""].Shed != 1 {
+		t.Errorf("shed: %+v", stats)
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	a := &AdaptiveOptions{Target: 100 * time.Millisecond, Min: 2, Max: 20, Backoff: 0.5}
+	l := newLimiter(10, a)
+	now := time.Now()
+	for i := 0; i < 300; i++ {
+		l.acquire(1)
+		l.release(time.Millisecond, false, now)
+	}
+	if got := l.stats().Limit; got != 20 {
+		t.Fatalf("limit after fast calls: got %d, want 20", got)
+	}
+
+	l.acquire(1)
+	l.release(time.Second, false, now)
+	if got := l.stats().Limit; got != 10 {
+		t.Fatalf("limit after slow call: got %d, want 10", got)
+	}
+	// Target时间内只减小一次
+	l.acquire(1)
+	l.release(time.Second, false, now.Add(time.Millisecond))
+	if got := l.stats().Limit; got != 10 {
+		t.Fatalf("limit after second slow call: got %d, want 10", got)
+	}
+	for i := 1; i <= 10; i++ {
+		l.acquire(1)
+		l.release(0, true, now.Add(time.Duration(i)*time.Second))
+	}
+	if got := l.stats().Limit; got != 2 {
+		t.Fatalf("limit after overload: got %d, want 2", got)
+	}
+}
+
+func TestLimiterMethodPath(t *testing.T) {
+	b := Blocker{started: make(chan struct{}, 10), release: make(chan struct{})}
+	s := NewServer(nil)
+	for _, prefix := range []string{"/blocker/v1", "/devices/{id}"} {
+		if err := s.Register(prefix, &b); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	if err := s.Alias("/blocker/v0", "/blocker/v1"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+	l := NewLimiter(LimitOptions{Methods: map[string]int{"/blocker/v1/Wait": 1, "/devices/{id}/Wait": 1}})
+	s.AddMiddleware(l)
+
+	call := func(path string) int {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(`{}`)))
+		return w.Code
+	}
+
+	// 通过别名和路径模板的调用计入注册路径的上限
+	var wg sync.WaitGroup
+	for _, path := range []string{"/blocker/v0/Wait", "/devices/1/Wait"} {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			call(path)
+		}(path)
+		<-b.started
+	}
+	stats := l.Stats()
+	if stats["/blocker/v1/Wait"].Inflight != 1 || stats["/devices/{id}/Wait"].Inflight != 1 {
+		close(b.release)
+		t.Fatalf("inflight: %+v", stats)
+	}
+	for _, path := range []string{"/blocker/v1/Wait", "/blocker/v0/Wait", "/devices/2/Wait"} {
+		if got, want := call(path), http.StatusServiceUnavailable; got != want {
+			t.Errorf("%s: status: got %d, want %d", path, got, want)
+		}
+	}
+	close(b.release)
+	wg.Wait()
+
+	// panic时释放占用的并发
+	func() {
+		defer func() { recover() }()
+		r := httptest.NewRequest("POST", "/devices/1/Wait", strings.NewReader(`{}`))
+		l.ServeHTTP(context.Background(), httptest.NewRecorder(), r, func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			panic("next")
+		})
+	}()
+	for path, stats := range l.Stats() {
+		if stats.Inflight != 0 {
+			t.Errorf("%s: inflight: got %d, want 0", path, stats.Inflight)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package loadtest 使用httprpc.Client对单个方法进行压力测试.
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+// Options 压测选项.
+//
+// Rate为0时Concurrency个调用方连续调用; Rate大于0时按每秒Rate次的速率发起调用,
+// 最多Concurrency个调用同时进行, 调用方都忙时该次调用被跳过并计入Skipped.
+// Concurrency为0时为1. 达到Duration或Requests时停止, 两者都为0时只调用一次.
+type Options struct {
+	URL         string
+	Path        string
+	Args        *Template // 为nil时不带参数
+	Header      http.Header
+	Concurrency int
+	Rate        float64
+	Duration    time.Duration
+	Requests    int
+}
+
+type sample struct {
+	latency time.Duration
+	code    codes.Code
+	failed  bool // 没有错误码的错误, 如网络错误
+}
+
+// Run 执行压测并返回结果, ctx取消时提前停止.
+//
+// 调用使用httprpc.HTTPClient, 并发较高时调用方应调大其Transport的MaxIdleConnsPerHost.
+func Run(ctx context.Context, o Options) (*Result, error) {
+	if o.URL == "" || o.Path == "" {
+		return nil, errors.New("url and path are required")
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+	if o.Duration <= 0 && o.Requests <= 0 {
+		o.Requests = 1
+	}
+	if o.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Duration)
+		defer cancel()
+	}
+
+	c := httprpc.NewClient(o.URL, nil)
+	var (
+		mu      sync.Mutex
+		samples []sample
+		issued  int
+		skipped int
+	)
+	// next 占用一次调用, 达到Requests时返回false
+	next := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if o.Requests > 0 && issued >= o.Requests {
+			return false
+		}
+		issued++
+		return true
+	}
+	call := func() {
+		s := callOnce(ctx, c, o)
+		mu.Lock()
+		samples = append(samples, s)
+		mu.Unlock()
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	if o.Rate > 0 {
+		tokens := make(chan struct{})
+		for i := 0; i < o.Concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range tokens {
+					call()
+				}
+			}()
+		}
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / o.Rate))
+	loop:
+		for next() {
+			select {
+			case tokens <- struct{}{}:
+			default:
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+			}
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-ticker.C:
+			}
+		}
+		ticker.Stop()
+		close(tokens)
+	} else {
+		for i := 0; i < o.Concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for ctx.Err() == nil && next() {
+					call()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return newResult(o, samples, skipped, time.Since(start)), nil
+}
+
+func callOnce(ctx context.Context, c *httprpc.Client, o Options) sample {
+	var args interface{}
+	if o.Args != nil {
+		if b := o.Args.Execute(); len(b) > 0 {
+			args = b
+		}
+	}
+	rctx := &httprpc.Context{Context: ctx, RequestHeader: o.Header}
+	var reply json.RawMessage
+	start := time.Now()
+	err := c.Call(rctx, o.Path, args, &reply)
+	s := sample{latency: time.Since(start)}
+	if err != nil {
+		var e httprpc.ErrorCode
+		if errors.As(err, &e) {
+			s.code = e.Code()
+		} else {
+			s.code, s.failed = codes.Unknown, true
+		}
+	}
+	return s
+}
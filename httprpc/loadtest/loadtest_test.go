@@ -0,0 +1,183 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type Args struct {
+	ID   int
+	Name string
+}
+
+type Echo struct {
+	mu   sync.Mutex
+	seen []int
+}
+
+func (e *Echo) Get(ctx context.Context, args Args, reply *Args) error {
+	e.mu.Lock()
+	e.seen = append(e.seen, args.ID)
+	e.mu.Unlock()
+	if args.ID%4 == 3 {
+		return httprpc.NewError(codes.Conflict, errors.New("odd"))
+	}
+	*reply = args
+	return nil
+}
+
+const codeBusy = codes.Code(-1902)
+
+func (e *Echo) Busy(ctx context.Context, args Args, reply *Args) error {
+	return httprpc.NewError(codeBusy, errors.New("busy"))
+}
+
+func TestRunTypedError(t *testing.T) {
+	// 注册的错误类型包装了*httprpc.Error
+	httprpc.RegisterError(codeBusy, func(info *httprpc.ErrorInfo) error {
+		return fmt.Errorf("echo: %w", httprpc.NewError(info.Code, errors.New(info.Cause)))
+	})
+	var e Echo
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/echo", &e); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tmpl, err := ParseTemplate(`{"ID": {{seq}}}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	res, err := Run(context.Background(), Options{URL: ts.URL, Path: "/echo/Busy", Args: tmpl, Concurrency: 2, Requests: 4})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Failed != 0 || res.Codes[codeBusy] != 4 {
+		t.Errorf("failed: %d, codes: %v", res.Failed, res.Codes)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{"ID": {{seq:10}}, "N": {{rand:5-5}}, "Name": "{{pick:a}}-{{uuid}}"}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	for i := 10; i < 13; i++ {
+		var v struct {
+			ID, N int
+			Name  string
+		}
+		if err := json.Unmarshal(tmpl.Execute(), &v); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if v.ID != i || v.N != 5 || !strings.HasPrefix(v.Name, "a-") || len(v.Name) != 38 {
+			t.Fatalf("execute %d: %+v", i, v)
+		}
+	}
+
+	for _, s := range []string{`{"ID": {{seq:x}}}`, `{{rand:9-1}}`, `{{pick}}`, `{{unknown}}`, `{"ID": {{pick:a}}}`} {
+		if _, err := ParseTemplate(s); err == nil {
+			t.Errorf("ParseTemplate(%s): return nil error", s)
+		} else {
+			t.Logf("ParseTemplate(%s): %v", s, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var e Echo
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/echo", &e); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tmpl, err := ParseTemplate(`{"ID": {{seq}}, "Name": "n{{rand:1-9}}"}`)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	res, err := Run(context.Background(), Options{URL: ts.URL, Path: "/echo/Get", Args: tmpl, Concurrency: 4, Requests: 40})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got, want := res.Requests, 40; got != want {
+		t.Fatalf("requests: got %d, want %d", got, want)
+	}
+	if got, want := res.Codes[codes.OK], 30; got != want {
+		t.Errorf("ok: got %d, want %d", got, want)
+	}
+	if got, want := res.Codes[codes.Conflict], 10; got != want {
+		t.Errorf("conflict: got %d, want %d", got, want)
+	}
+	if got, want := res.Errors, 10; got != want {
+		t.Errorf("errors: got %d, want %d", got, want)
+	}
+	if l := res.Latency; l.Min <= 0 || l.Min > l.P50 || l.P50 > l.P99 || l.P99 > l.Max {
+		t.Errorf("latency: %+v", l)
+	}
+	seen := make(map[int]bool)
+	for _, id := range e.seen {
+		seen[id] = true
+	}
+	if got, want := len(seen), 40; got != want {
+		t.Errorf("distinct seq: got %d, want %d", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := res.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	back, err := ReadResult(&buf)
+	if err != nil {
+		t.Fatalf("ReadResult: %v", err)
+	}
+	if got, want := back.Codes[codes.Conflict], 10; got != want {
+		t.Errorf("decoded conflict: got %d, want %d", got, want)
+	}
+	buf.Reset()
+	res.Print(&buf)
+	res.Compare(&buf, back)
+	t.Logf("\n%s", buf.String())
+}
+
+func TestRunRate(t *testing.T) {
+	var e Echo
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/echo", &e); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := Run(context.Background(), Options{URL: ts.URL, Path: "/echo/Get", Rate: 100, Concurrency: 2, Duration: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if n := res.Requests + res.Skipped; n < 15 || n > 35 {
+		t.Errorf("requests: %d, skipped: %d, want about 30 in total", res.Requests, res.Skipped)
+	}
+	if res.Elapsed < 0.3 {
+		t.Errorf("elapsed: %v", res.Elapsed)
+	}
+
+	res, err = Run(context.Background(), Options{URL: "http://127.0.0.1:1", Path: "/echo/Get", Requests: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.Failed != 2 || res.Codes[codes.Unknown] != 2 {
+		t.Errorf("failed: %d, codes: %v", res.Failed, res.Codes)
+	}
+}
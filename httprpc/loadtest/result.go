@@ -0,0 +1,160 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+// Latency 调用延迟统计, 单位为毫秒
+type Latency struct {
+	Min  float64
+	Mean float64
+	P50  float64
+	P90  float64
+	P95  float64
+	P99  float64
+	Max  float64
+}
+
+// Result 压测结果, 可以编码为JSON用于不同版本之间的对比.
+// Codes按错误码统计调用次数, 成功的调用计入codes.OK; Failed为没有错误码的错误次数, 同时计入codes.Unknown.
+type Result struct {
+	Path        string
+	Concurrency int
+	Rate        float64 `json:",omitempty"`
+	Requests    int
+	Errors      int
+	Failed      int
+	Skipped     int     `json:",omitempty"`
+	Elapsed     float64 // 秒
+	Throughput  float64 // 每秒完成的调用数
+	Latency     Latency
+	Codes       map[codes.Code]int
+}
+
+func newResult(o Options, samples []sample, skipped int, elapsed time.Duration) *Result {
+	r := &Result{
+		Path:        o.Path,
+		Concurrency: o.Concurrency,
+		Rate:        o.Rate,
+		Requests:    len(samples),
+		Skipped:     skipped,
+		Elapsed:     elapsed.Seconds(),
+		Codes:       make(map[codes.Code]int),
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(len(samples)) / elapsed.Seconds()
+	}
+	latencies := make([]time.Duration, len(samples))
+	var total time.Duration
+	for i, s := range samples {
+		latencies[i] = s.latency
+		total += s.latency
+		r.Codes[s.code]++
+		if s.code != codes.OK {
+			r.Errors++
+		}
+		if s.failed {
+			r.Failed++
+		}
+	}
+	if len(latencies) <= 0 {
+		return r
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	r.Latency = Latency{
+		Min:  ms(latencies[0]),
+		Mean: ms(total / time.Duration(len(latencies))),
+		P50:  ms(percentile(latencies, 50)),
+		P90:  ms(percentile(latencies, 90)),
+		P95:  ms(percentile(latencies, 95)),
+		P99:  ms(percentile(latencies, 99)),
+		Max:  ms(latencies[len(latencies)-1]),
+	}
+	return r
+}
+
+// percentile 使用nearest-rank方法, sorted已按升序排列
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*1000) / 1000
+}
+
+// WriteJSON 将结果编码为JSON
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadResult 读取WriteJSON写入的结果
+func ReadResult(rd io.Reader) (*Result, error) {
+	var r Result
+	if err := json.NewDecoder(rd).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Print 输出可读的报告
+func (r *Result) Print(w io.Writer) {
+	fmt.Fprintf(w, "path:        %s\n", r.Path)
+	fmt.Fprintf(w, "requests:    %d in %.2fs, %d errors", r.Requests, r.Elapsed, r.Errors)
+	if r.Skipped > 0 {
+		fmt.Fprintf(w, ", %d skipped", r.Skipped)
+	}
+	fmt.Fprintf(w, "\nthroughput:  %.1f/s\n", r.Throughput)
+	l := r.Latency
+	fmt.Fprintf(w, "latency(ms): min %.2f, mean %.2f, p50 %.2f, p90 %.2f, p95 %.2f, p99 %.2f, max %.2f\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	fmt.Fprintf(w, "codes:\n")
+	list := make([]codes.Code, 0, len(r.Codes))
+	for c := range r.Codes {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] > list[j] })
+	for _, c := range list {
+		fmt.Fprintf(w, "  %6d %-28s %d\n", c, c, r.Codes[c])
+	}
+	if r.Failed > 0 {
+		fmt.Fprintf(w, "  %d calls failed without a code (network or decode errors)\n", r.Failed)
+	}
+}
+
+// Compare 输出r相对base的变化, 用于对比不同版本的压测结果.
+func (r *Result) Compare(w io.Writer, base *Result) {
+	row := func(name string, old, cur float64) {
+		change := "n/a"
+		if old != 0 {
+			change = fmt.Sprintf("%+.1f%%", (cur-old)/old*100)
+		}
+		fmt.Fprintf(w, "%-12s %12.2f %12.2f %10s\n", name, old, cur, change)
+	}
+	fmt.Fprintf(w, "%-12s %12s %12s %10s\n", "", "base", "current", "change")
+	row("throughput", base.Throughput, r.Throughput)
+	row("errors(%)", errorRate(base), errorRate(r))
+	row("p50(ms)", base.Latency.P50, r.Latency.P50)
+	row("p90(ms)", base.Latency.P90, r.Latency.P90)
+	row("p99(ms)", base.Latency.P99, r.Latency.P99)
+	row("max(ms)", base.Latency.Max, r.Latency.Max)
+}
+
+func errorRate(r *Result) float64 {
+	if r.Requests <= 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests) * 100
+}
@@ -0,0 +1,124 @@
+package loadtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ironzhang/pearls/uuid"
+)
+
+// 占位符的格式为{{name}}或{{name:arg}}
+var placeholder = regexp.MustCompile(`\{\{\s*(\w+)(?::([^}]*))?\s*\}\}`)
+
+// Template 参数模板, 每次调用时替换其中的占位符:
+//
+//	{{seq}}        从0开始递增的整数, {{seq:100}}从100开始
+//	{{rand}}       [0, 1000000)内的随机整数, {{rand:10-20}}为[10, 20]内的随机整数
+//	{{pick:a|b|c}} 随机选取其中一个字符串, 不带引号
+//	{{uuid}}       随机UUID, 不带引号
+//
+// 替换后的结果必须是合法的JSON.
+type Template struct {
+	parts []func(buf *bytes.Buffer, sample bool)
+}
+
+// ParseTemplate 解析参数模板, 并检查替换后的结果是否为合法的JSON.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{}
+	last := 0
+	for _, m := range placeholder.FindAllStringSubmatchIndex(s, -1) {
+		literal := s[last:m[0]]
+		t.parts = append(t.parts, func(buf *bytes.Buffer, sample bool) { buf.WriteString(literal) })
+		name, arg := s[m[2]:m[3]], ""
+		if m[4] >= 0 {
+			arg = s[m[4]:m[5]]
+		}
+		part, err := newPart(name, strings.TrimSpace(arg))
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %v", s[m[0]:m[1]], err)
+		}
+		t.parts = append(t.parts, part)
+		last = m[1]
+	}
+	literal := s[last:]
+	t.parts = append(t.parts, func(buf *bytes.Buffer, sample bool) { buf.WriteString(literal) })
+
+	if b := t.execute(true); len(b) > 0 && !json.Valid(b) {
+		return nil, fmt.Errorf("template result is not valid JSON: %s", b)
+	}
+	return t, nil
+}
+
+func newPart(name, arg string) (func(buf *bytes.Buffer, sample bool), error) {
+	switch name {
+	case "seq":
+		var start int64
+		if arg != "" {
+			var err error
+			if start, err = strconv.ParseInt(arg, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid seq start %q", arg)
+			}
+		}
+		n := start - 1
+		return func(buf *bytes.Buffer, sample bool) {
+			if sample {
+				buf.WriteString(strconv.FormatInt(start, 10))
+				return
+			}
+			buf.WriteString(strconv.FormatInt(atomic.AddInt64(&n, 1), 10))
+		}, nil
+
+	case "rand":
+		min, max := int64(0), int64(999999)
+		if arg != "" {
+			i := strings.Index(arg[1:], "-") + 1
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid rand range %q, must be <min>-<max>", arg)
+			}
+			var err1, err2 error
+			min, err1 = strconv.ParseInt(arg[:i], 10, 64)
+			max, err2 = strconv.ParseInt(arg[i+1:], 10, 64)
+			if err1 != nil || err2 != nil || min > max {
+				return nil, fmt.Errorf("invalid rand range %q, must be <min>-<max>", arg)
+			}
+		}
+		return func(buf *bytes.Buffer, sample bool) {
+			buf.WriteString(strconv.FormatInt(min+rand.Int63n(max-min+1), 10))
+		}, nil
+
+	case "pick":
+		choices := strings.Split(arg, "|")
+		if arg == "" {
+			return nil, fmt.Errorf("pick needs at least one choice")
+		}
+		return func(buf *bytes.Buffer, sample bool) {
+			buf.WriteString(choices[rand.Int63n(int64(len(choices)))])
+		}, nil
+
+	case "uuid":
+		return func(buf *bytes.Buffer, sample bool) {
+			buf.WriteString(uuid.New().String())
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown placeholder %s", name)
+}
+
+// Execute 替换占位符后返回参数, 可以并发调用.
+func (t *Template) Execute() json.RawMessage {
+	return t.execute(false)
+}
+
+// execute sample为true时生成示例结果, 不推进序列
+func (t *Template) execute(sample bool) json.RawMessage {
+	var buf bytes.Buffer
+	for _, part := range t.parts {
+		part(&buf, sample)
+	}
+	return bytes.TrimSpace(buf.Bytes())
+}
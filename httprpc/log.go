@@ -0,0 +1,22 @@
+package httprpc
+
+import (
+	"sync/atomic"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/zaplog"
+)
+
+var logger atomic.Value
+
+// SetLogger 设置httprpc内部使用的日志, 为nil时使用zaplog.Std.
+func SetLogger(l *zaplog.Logger) {
+	logger.Store(&l)
+}
+
+// Logger 返回httprpc内部使用的日志
+func Logger() *zaplog.Logger {
+	if p, ok := logger.Load().(**zaplog.Logger); ok && *p != nil {
+		return *p
+	}
+	return zaplog.Std
+}
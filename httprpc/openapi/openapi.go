@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+const Version = "3.0.3"
+
+const (
+	octetStream   = "application/octet-stream"
+	multipartForm = "multipart/form-data"
+)
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty"`
+	Post *Operation `json:"post,omitempty"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Options 文档生成选项
+type Options struct {
+	Info    Info
+	Servers []Server
+}
+
+// Generate 根据Server中已注册的方法生成OpenAPI 3文档.
+//
+// 每个方法对应一个POST操作, 参数和返回值类型生成为components中的schema,
+// 已注册的错误码按HTTP状态分组生成错误响应. args或reply为io.Reader或Stream时请求体或响应体为
+// application/octet-stream, args中有文件字段时请求体为multipart/form-data.
+func Generate(s *httprpc.Server, opts Options) *Document {
+	codec := s.Codec()
+	if codec == nil {
+		codec = httprpc.DefaultCodec
+	}
+	contentType := codec.ContentType()
+
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    opts.Info,
+		Servers: opts.Servers,
+		Paths:   make(map[string]*PathItem),
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "httprpc"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "v0"
+	}
+
+	errors := errorResponses(g.errReply(), contentType)
+	for _, m := range s.Methods() {
+		op := &Operation{
+			OperationID: operationID(m.Path),
+			Tags:        []string{m.Class},
+			Responses:   make(map[string]*Response),
+			Deprecated:  m.Deprecated,
+		}
+		if m.Alias != "" {
+			op.Summary = "alias of " + m.Alias
+		}
+		op.Parameters = g.pathParameters(m.Args, m.PathParams)
+		if isStream(m.Args) {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{octetStream: {Schema: binarySchema()}},
+			}
+		} else if args := g.schemaOf(m.Args); args != nil {
+			ct := contentType
+			if hasFiles(m.Args) {
+				ct = multipartForm
+			}
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{ct: {Schema: args}},
+			}
+		}
+		ok := &Response{Description: codes.OK.String()}
+		if isStream(m.Reply) {
+			ok.Content = map[string]MediaType{octetStream: {Schema: binarySchema()}}
+		} else if reply := g.schemaOf(m.Reply); reply != nil {
+			ok.Content = map[string]MediaType{contentType: {Schema: reply}}
+		}
+		op.Responses[strconv.Itoa(http.StatusOK)] = ok
+		for status, r := range errors {
+			op.Responses[status] = r
+		}
+		item := &PathItem{Post: op}
+		if m.ReadOnly {
+			get := *op
+			get.OperationID = op.OperationID + ".get"
+			get.RequestBody = nil
+			get.Parameters = append(g.pathParameters(m.Args, m.PathParams), g.queryParameters(m.Args)...)
+			get.Responses = map[string]*Response{strconv.Itoa(http.StatusNotModified): {Description: "not modified"}}
+			for status, r := range op.Responses {
+				get.Responses[status] = r
+			}
+			item.Get = &get
+		}
+		doc.Paths[m.Path] = item
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func errorResponses(schema *Schema, contentType string) map[string]*Response {
+	descs := make(map[int][]string)
+	for _, c := range codes.Codes() {
+		if c == codes.OK {
+			continue
+		}
+		descs[c.Status()] = append(descs[c.Status()], strconv.Itoa(int(c))+": "+c.String())
+	}
+	responses := make(map[string]*Response, len(descs))
+	for status, list := range descs {
+		sort.Strings(list)
+		responses[strconv.Itoa(status)] = &Response{
+			Description: strings.Join(list, "; "),
+			Content:     map[string]MediaType{contentType: {Schema: schema}},
+		}
+	}
+	return responses
+}
+
+func operationID(path string) string {
+	return strings.Replace(strings.Trim(path, "/"), "/", ".", -1)
+}
+
+// Handler 返回输出OpenAPI文档的http.Handler, 每次请求时根据Server的当前状态生成.
+func Handler(s *httprpc.Server, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(Generate(s, opts))
+	})
+}
@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+)
+
+type Base struct {
+	ID      int64
+	Created time.Time
+}
+
+type Args struct {
+	Base
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags,omitempty"`
+	Next  *Args    `json:"next,omitempty"`
+	Skip  int      `json:"-"`
+	inner int
+}
+
+type Reply struct {
+	Items map[string]int
+}
+
+type Device int
+
+func (d *Device) Get(ctx context.Context, args *Args, reply *Reply) error {
+	return nil
+}
+
+func (d *Device) Ping(ctx context.Context, args interface{}, reply interface{}) error {
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	var d Device
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/device/v1", &d); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	doc := Generate(s, Options{Info: Info{Title: "device", Version: "v1"}})
+
+	get, ok := doc.Paths["/device/v1/Get"]
+	if !ok || get.Post == nil {
+		t.Fatalf("/device/v1/Get operation not found")
+	}
+	if got, want := get.Post.RequestBody.Content["application/json"].Schema.Ref, "#/components/schemas/Args"; got != want {
+		t.Errorf("args ref: got %v, want %v", got, want)
+	}
+	if _, ok := get.Post.Responses["400"]; !ok {
+		t.Errorf("400 response not found")
+	}
+	ping := doc.Paths["/device/v1/Ping"]
+	if ping == nil || ping.Post.RequestBody != nil || ping.Post.Responses["200"].Content != nil {
+		t.Errorf("ping operation: %+v", ping)
+	}
+
+	args := doc.Components.Schemas["Args"]
+	if args == nil {
+		t.Fatalf("Args schema not found")
+	}
+	for _, name := range []string{"ID", "Created", "name", "tags", "next"} {
+		if _, ok := args.Properties[name]; !ok {
+			t.Errorf("Args property %q not found", name)
+		}
+	}
+	for _, name := range []string{"Skip", "inner", "Base"} {
+		if _, ok := args.Properties[name]; ok {
+			t.Errorf("Args property %q should not be generated", name)
+		}
+	}
+	if got, want := args.Properties["Created"].Format, "date-time"; got != want {
+		t.Errorf("Created format: got %v, want %v", got, want)
+	}
+	if got, want := args.Properties["next"].Ref, "#/components/schemas/Args"; got != want {
+		t.Errorf("next ref: got %v, want %v", got, want)
+	}
+	if got, want := doc.Components.Schemas["Reply"].Properties["Items"].AdditionalProperties.Type, "integer"; got != want {
+		t.Errorf("Items type: got %v, want %v", got, want)
+	}
+	if _, ok := doc.Components.Schemas["ErrReply"].Properties["Details"]; !ok {
+		t.Errorf("ErrReply property Details not found")
+	}
+}
+
+type RebootArgs struct {
+	ID    int64 `path:"id"`
+	Force bool
+}
+
+type Devices int
+
+func (d *Devices) Reboot(ctx context.Context, args *RebootArgs, reply *Reply) error {
+	return nil
+}
+
+func TestGeneratePathParams(t *testing.T) {
+	var d Devices
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/devices/{id}/{slot}", &d); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetReadOnly("/devices/{id}/{slot}/Reboot"); err != nil {
+		t.Fatalf("SetReadOnly: %v", err)
+	}
+	doc := Generate(s, Options{})
+	item, ok := doc.Paths["/devices/{id}/{slot}/Reboot"]
+	if !ok {
+		t.Fatalf("/devices/{id}/{slot}/Reboot operation not found")
+	}
+	params := item.Post.Parameters
+	if got, want := len(params), 2; got != want {
+		t.Fatalf("parameters: got %d, want %d", got, want)
+	}
+	for i, want := range []Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}},
+		{Name: "slot", In: "path", Required: true, Schema: &Schema{Type: "string"}},
+	} {
+		if got := params[i]; !reflect.DeepEqual(*got, want) {
+			t.Errorf("parameter %d: got %+v %+v, want %+v %+v", i, got, got.Schema, want, want.Schema)
+		}
+	}
+
+	// GET请求中带path tag的字段不作为查询参数
+	var names []string
+	for _, p := range item.Get.Parameters {
+		names = append(names, p.In+":"+p.Name)
+	}
+	if got, want := names, []string{"path:id", "path:slot", "query:Force"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GET parameters: got %v, want %v", got, want)
+	}
+}
+
+type UploadReply struct {
+	Size int64
+}
+
+type ImageArgs struct {
+	Version string
+	Image   *multipart.FileHeader
+	Notes   []*multipart.FileHeader
+}
+
+type Firmware int
+
+func (f *Firmware) Upload(ctx context.Context, r io.Reader) (UploadReply, error) {
+	return UploadReply{}, nil
+}
+
+func (f *Firmware) Image(ctx context.Context, args *ImageArgs) (UploadReply, error) {
+	return UploadReply{}, nil
+}
+
+func (f *Firmware) Download(ctx context.Context, name string) (*httprpc.Stream, error) {
+	return nil, nil
+}
+
+func TestGenerateStream(t *testing.T) {
+	var f Firmware
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/firmware", &f); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	doc := Generate(s, Options{})
+	binary := &Schema{Type: "string", Format: "binary"}
+
+	upload := doc.Paths["/firmware/Upload"].Post
+	if got := upload.RequestBody.Content["application/octet-stream"].Schema; !reflect.DeepEqual(got, binary) {
+		t.Errorf("Upload args: got %+v, want %+v", upload.RequestBody.Content, binary)
+	}
+	download := doc.Paths["/firmware/Download"].Post
+	if got := download.Responses["200"].Content["application/octet-stream"].Schema; !reflect.DeepEqual(got, binary) {
+		t.Errorf("Download reply: got %+v, want %+v", download.Responses["200"].Content, binary)
+	}
+	image := doc.Paths["/firmware/Image"].Post
+	if got, want := image.RequestBody.Content["multipart/form-data"].Schema.Ref, "#/components/schemas/ImageArgs"; got != want {
+		t.Errorf("Image args: got %+v, want %v", image.RequestBody.Content, want)
+	}
+	args := doc.Components.Schemas["ImageArgs"]
+	if got := args.Properties["Image"]; got.Type != "string" || got.Format != "binary" {
+		t.Errorf("Image: got %+v, want binary", got)
+	}
+	if got := args.Properties["Notes"].Items; got.Type != "string" || got.Format != "binary" {
+		t.Errorf("Notes: got %+v, want binary", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var d Device
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/device", &d); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	w := httptest.NewRecorder()
+	Handler(s, Options{}).ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %v", w.Code)
+	}
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("json unmarshal: %v", err)
+	}
+	if got, want := doc.OpenAPI, Version; got != want {
+		t.Errorf("openapi: got %v, want %v", got, want)
+	}
+	if got, want := len(doc.Paths), 2; got != want {
+		t.Errorf("paths: got %v, want %v", got, want)
+	}
+}
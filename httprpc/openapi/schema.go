@@ -0,0 +1,272 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/internal/jsonfield"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+var (
+	typeOfTime          = reflect.TypeOf(time.Time{})
+	typeOfRawMessage    = reflect.TypeOf(json.RawMessage{})
+	typeOfJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	typeOfReader        = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	typeOfReadCloser    = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+	typeOfStream        = reflect.TypeOf(httprpc.Stream{})
+	typeOfFileHeader    = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// isStream args或reply为io.Reader、io.ReadCloser或Stream时, 请求体或响应体不经过Codec
+func isStream(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == typeOfReader || t == typeOfReadCloser || t == typeOfStream
+}
+
+// hasFiles args中有*multipart.FileHeader或[]*multipart.FileHeader字段时, 请求体可以是multipart/form-data
+func hasFiles(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for _, f := range jsonfield.Fields(t) {
+		ft := f.Type
+		if ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Ptr && ft.Elem() == typeOfFileHeader {
+			return true
+		}
+	}
+	return false
+}
+
+func binarySchema() *Schema {
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *generator) errReply() *Schema {
+	const name = "ErrReply"
+	if _, ok := g.schemas[name]; !ok {
+		g.schemas[name] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"Code":    {Type: "integer", Format: "int32"},
+				"Error":   {Type: "string"},
+				"Cause":   {Type: "string"},
+				"Details": {Description: "error details returned by errors implementing httprpc.ErrorDetails"},
+				"Stack":   {Type: "string"},
+			},
+			Required: []string{"Code", "Error", "Cause"},
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// schemaOf 生成方法参数或返回值类型的schema, interface{}类型返回nil.
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return g.schema(t)
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		s := g.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	}
+
+	switch {
+	case t == typeOfTime:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == typeOfFileHeader:
+		return binarySchema()
+	case t == typeOfRawMessage:
+		return &Schema{}
+	case t.Implements(typeOfJSONMarshaler) || reflect.PtrTo(t).Implements(typeOfJSONMarshaler):
+		return &Schema{}
+	case t.Implements(typeOfTextMarshaler) || reflect.PtrTo(t).Implements(typeOfTextMarshaler):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: new(float64)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		return g.structRef(t)
+	}
+	return &Schema{}
+}
+
+func (g *generator) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.structSchema(t)
+	}
+	name, ok := g.names[t]
+	if !ok {
+		name = g.componentName(t)
+		g.names[t] = name
+		// 先占位, 避免递归类型无限展开
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (g *generator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, ok := g.schemas[name]; !ok {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	base := pkg + "." + name
+	name = base
+	for i := 2; ; i++ {
+		if _, ok := g.schemas[name]; !ok {
+			return name
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range jsonfield.Fields(t) {
+		s.Properties[f.Name] = g.schema(f.Type)
+		if !f.OmitEmpty && f.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s
+}
+
+// queryParameters 生成只读方法GET请求的查询参数, 与httprpc的绑定规则一致. 带path tag的字段从路径绑定, 不生成查询参数.
+func (g *generator) queryParameters(t reflect.Type) []*Parameter {
+	if t.Kind() == reflect.Interface {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var params []*Parameter
+	if t.Kind() != reflect.Struct || t == typeOfTime {
+		return []*Parameter{{Name: "args", In: "query", Schema: g.schema(t)}}
+	}
+	g.collectParameters(t, "", &params)
+	return params
+}
+
+func (g *generator) collectParameters(t reflect.Type, prefix string, params *[]*Parameter) {
+	for _, f := range jsonfield.Fields(t) {
+		if prefix == "" && len(f.Index) == 1 && t.Field(f.Index[0]).Tag.Get("path") != "" {
+			continue
+		}
+		name := f.Name
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != typeOfTime && !reflect.PtrTo(ft).Implements(typeOfTextMarshaler) {
+			g.collectParameters(ft, name, params)
+			continue
+		}
+		*params = append(*params, &Parameter{Name: name, In: "query", Schema: g.schema(ft)})
+	}
+}
+
+// pathParameters 生成路径模板中的参数, 类型取args中对应path tag的字段, 没有时为字符串.
+func (g *generator) pathParameters(args reflect.Type, names []string) []*Parameter {
+	if len(names) <= 0 {
+		return nil
+	}
+	for args.Kind() == reflect.Ptr {
+		args = args.Elem()
+	}
+	params := make([]*Parameter, 0, len(names))
+	for _, name := range names {
+		schema := &Schema{Type: "string"}
+		if args.Kind() == reflect.Struct {
+			for i := 0; i < args.NumField(); i++ {
+				if f := args.Field(i); f.Tag.Get("path") == name {
+					schema = g.schema(f.Type)
+					break
+				}
+			}
+		}
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return params
+}
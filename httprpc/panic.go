@@ -0,0 +1,108 @@
+package httprpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+// PanicInfo 方法panic时的信息
+type PanicInfo struct {
+	Path   string
+	Class  string
+	Method string
+	Value  interface{}
+	Stack  []byte
+}
+
+// PanicHandler 处理方法中恢复的panic
+type PanicHandler func(ctx context.Context, info *PanicInfo)
+
+// LogPanic 默认的PanicHandler, 通过zaplog输出带trace id的错误日志.
+func LogPanic(ctx context.Context, info *PanicInfo) {
+	Logger().WithContext(ctx).WithFunction("httprpc").Errorw("panic", "path", info.Path,
+		"class", info.Class, "method", info.Method, "panic", fmt.Sprint(info.Value), "stack", string(info.Stack))
+}
+
+// PanicOptions panic处理选项.
+//
+// panic的调用栈是否返回给调用方由Server的StackPolicy决定. DisableAfter大于0时, 方法在Window内
+// panic达到DisableAfter次后在DisableFor时间内直接返回codes.Unavailable.
+type PanicOptions struct {
+	Handler      PanicHandler
+	DisableAfter int
+	Window       time.Duration
+	DisableFor   time.Duration
+}
+
+// SetPanicOptions 设置panic处理选项, Handler为nil时使用LogPanic, 需在开始服务前调用.
+func (s *Server) SetPanicOptions(o PanicOptions) {
+	s.panicOptions = o
+}
+
+// panicState 方法最近的panic次数及禁用时间
+type panicState struct {
+	mu            sync.Mutex
+	windowStart   time.Time
+	count         int
+	disabledUntil time.Time
+}
+
+func (p *panicState) disabled(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return now.Before(p.disabledUntil)
+}
+
+// record 记录一次panic, 返回方法是否因此被禁用.
+func (p *panicState) record(now time.Time, o *PanicOptions) bool {
+	if o.DisableAfter <= 0 {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.windowStart.IsZero() || now.Sub(p.windowStart) > o.Window {
+		p.windowStart, p.count = now, 0
+	}
+	p.count++
+	if p.count < o.DisableAfter {
+		return false
+	}
+	p.windowStart, p.count = time.Time{}, 0
+	p.disabledUntil = now.Add(o.DisableFor)
+	return true
+}
+
+func (s *Server) checkDisabled(meth *method) error {
+	if meth.panics.disabled(time.Now()) {
+		return Errorf(codes.Unavailable, "method %s is temporarily disabled after repeated panics", meth.path)
+	}
+	return nil
+}
+
+func (s *Server) recoverPanic(ctx context.Context, meth *method, r interface{}) error {
+	o := &s.panicOptions
+	pcs := callers(3)
+	stack := DefaultStackFilter.format(pcs)
+	className, methodName := splitPath(meth.path)
+	info := &PanicInfo{Path: meth.path, Class: className, Method: methodName, Value: r, Stack: stack}
+	handler := o.Handler
+	if handler == nil {
+		handler = LogPanic
+	}
+	handler(ctx, info)
+
+	if meth.panics.record(time.Now(), o) {
+		Logger().WithContext(ctx).WithFunction("httprpc").Warnw("method disabled after repeated panics",
+			"path", meth.path, "disableFor", o.DisableFor)
+	}
+
+	cause, ok := r.(error)
+	if !ok {
+		cause = fmt.Errorf("%v", r)
+	}
+	return &Error{code: codes.Panic, cause: cause, pcs: pcs}
+}
@@ -0,0 +1,96 @@
+package httprpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type Panicker struct{}
+
+func (Panicker) Panic(ctx context.Context, args *Args, reply *Reply) error {
+	if args.A > 0 {
+		panic("boom")
+	}
+	return nil
+}
+
+func TestPanicHandler(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/panicker", Panicker{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	s.SetStackPolicy(DevelopmentStack)
+	var mu sync.Mutex
+	var infos []*PanicInfo
+	s.SetPanicOptions(PanicOptions{
+		Handler: func(ctx context.Context, info *PanicInfo) {
+			mu.Lock()
+			infos = append(infos, info)
+			mu.Unlock()
+		},
+		DisableAfter: 2,
+		Window:       time.Minute,
+		DisableFor:   50 * time.Millisecond,
+	})
+
+	call := func(a int) *Error {
+		r, _ := serveTestHTTP(s, "POST", "/panicker/Panic", []byte(fmt.Sprintf(`{"A":%d}`, a)))
+		if r.Code == http.StatusOK {
+			return nil
+		}
+		var er errReply
+		if err := s.codec.Decode(r.Body, &er); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return &Error{code: codes.Code(er.Code), stack: []byte(er.Stack)}
+	}
+
+	e := call(1)
+	if e == nil || e.code != codes.Panic || len(e.stack) <= 0 {
+		t.Fatalf("first panic: got %+v", e)
+	}
+	if len(infos) != 1 || infos[0].Path != "/panicker/Panic" || infos[0].Class != "/panicker" ||
+		infos[0].Method != "Panic" || infos[0].Value != "boom" || len(infos[0].Stack) <= 0 {
+		t.Fatalf("panic info: %+v", infos)
+	}
+
+	if e = call(1); e == nil || e.code != codes.Panic {
+		t.Fatalf("second panic: got %+v", e)
+	}
+	if e = call(0); e == nil || e.code != codes.Unavailable {
+		t.Fatalf("disabled: got %+v", e)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if e = call(0); e != nil {
+		t.Fatalf("after disabled: got %+v", e)
+	}
+}
+
+func TestPanicState(t *testing.T) {
+	o := PanicOptions{DisableAfter: 2, Window: time.Second, DisableFor: time.Minute}
+	var p panicState
+	now := time.Now()
+	if p.record(now, &o) {
+		t.Fatalf("disabled after 1 panic")
+	}
+	// 超出窗口重新计数
+	if p.record(now.Add(2*time.Second), &o) {
+		t.Fatalf("disabled after window expired")
+	}
+	if !p.record(now.Add(2500*time.Millisecond), &o) {
+		t.Fatalf("not disabled after 2 panics")
+	}
+	if !p.disabled(now.Add(3 * time.Second)) {
+		t.Fatalf("not disabled")
+	}
+	if p.disabled(now.Add(2*time.Minute + 3*time.Second)) {
+		t.Fatalf("still disabled")
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"reflect"
+	"sync"
 	"unicode"
 	"unicode/utf8"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/internal/jsonfield"
 )
 
 var (
@@ -39,26 +41,15 @@ func checkIns(m reflect.Method) (in0, in1, in2, in3 reflect.Type, err error) {
 		err = fmt.Errorf("method %s has wrong number of ins: %d", m.Name, mtype.NumIn())
 		return
 	}
-	in0 = mtype.In(0)
-	in1 = mtype.In(1)
-	if !in1.Implements(typeOfContext) {
-		err = fmt.Errorf("method %s context type not implements context.Context: %s", m.Name, in1)
-		return
-	}
-	in2 = mtype.In(2)
-	if !isExportedOrBuiltinType(in2) {
-		err = fmt.Errorf("method %s args type not exported: %s", m.Name, in2)
+	desc := "method " + m.Name
+	in0, in1, in2, in3 = mtype.In(0), mtype.In(1), mtype.In(2), mtype.In(3)
+	if err = checkContext(desc, in1); err != nil {
 		return
 	}
-	in3 = mtype.In(3)
-	if in3.Kind() != reflect.Ptr && in3 != typeOfNilInterface {
-		err = fmt.Errorf("method %s reply type not a pointer or interface{}: %s", m.Name, in3)
-		return
-	}
-	if !isExportedOrBuiltinType(in3) {
-		err = fmt.Errorf("method %s reply type not exported: %s", m.Name, in3)
+	if err = checkArgs(desc, in2); err != nil {
 		return
 	}
+	err = checkReply(desc, in3)
 	return
 }
 
@@ -74,34 +65,176 @@ func checkOuts(m reflect.Method) error {
 	return nil
 }
 
+func checkContext(desc string, t reflect.Type) error {
+	if !t.Implements(typeOfContext) {
+		return fmt.Errorf("%s context type not implements context.Context: %s", desc, t)
+	}
+	return nil
+}
+
+func checkArgs(desc string, t reflect.Type) error {
+	if !isExportedOrBuiltinType(t) {
+		return fmt.Errorf("%s args type not exported: %s", desc, t)
+	}
+	return nil
+}
+
+func checkReply(desc string, t reflect.Type) error {
+	if t.Kind() != reflect.Ptr && t != typeOfNilInterface {
+		return fmt.Errorf("%s reply type not a pointer or interface{}: %s", desc, t)
+	}
+	if !isExportedOrBuiltinType(t) {
+		return fmt.Errorf("%s reply type not exported: %s", desc, t)
+	}
+	return nil
+}
+
+// signature 方法或函数去掉接收者后的签名, 支持以下形式:
+//
+//	func(ctx, args, *reply) error
+//	func(ctx, *reply) error
+//	func(ctx, args) (reply, error)
+//	func(ctx) (reply, error)
+//
+// 没有args时args为interface{}, 返回reply时reply为指向返回值类型的指针.
+type signature struct {
+	args    reflect.Type
+	reply   reflect.Type
+	noArgs  bool
+	returns bool
+}
+
+// checkSignature 检查ftype跳过前skip个参数后的签名, desc用于错误信息, 如"method Get".
+func checkSignature(desc string, ftype reflect.Type, skip int) (sig signature, err error) {
+	nin, nout := ftype.NumIn()-skip, ftype.NumOut()
+	if nin < 1 || nin > 3 {
+		return sig, fmt.Errorf("%s has wrong number of ins: %d", desc, ftype.NumIn())
+	}
+	if err = checkContext(desc, ftype.In(skip)); err != nil {
+		return
+	}
+	switch nout {
+	case 1:
+		if nin == 1 {
+			return sig, fmt.Errorf("%s has neither args nor reply", desc)
+		}
+		if out0 := ftype.Out(0); out0 != typeOfError {
+			return sig, fmt.Errorf("%s returns %s not error", desc, out0)
+		}
+		sig.args, sig.noArgs = typeOfNilInterface, nin == 2
+		if !sig.noArgs {
+			sig.args = ftype.In(skip + 1)
+		}
+		sig.reply = ftype.In(skip + nin - 1)
+	case 2:
+		if nin == 3 {
+			return sig, fmt.Errorf("%s returns reply but has wrong number of ins: %d", desc, ftype.NumIn())
+		}
+		if out1 := ftype.Out(1); out1 != typeOfError {
+			return sig, fmt.Errorf("%s second return type %s not error", desc, out1)
+		}
+		out0 := ftype.Out(0)
+		if !isExportedOrBuiltinType(out0) {
+			return sig, fmt.Errorf("%s reply type not exported: %s", desc, out0)
+		}
+		sig.args, sig.noArgs = typeOfNilInterface, nin == 1
+		if !sig.noArgs {
+			sig.args = ftype.In(skip + 1)
+		}
+		sig.reply, sig.returns = reflect.PtrTo(out0), true
+		return sig, checkArgs(desc, sig.args)
+	default:
+		return sig, fmt.Errorf("%s has wrong number of outs: %d", desc, nout)
+	}
+	if err = checkArgs(desc, sig.args); err != nil {
+		return
+	}
+	return sig, checkReply(desc, sig.reply)
+}
+
 type method struct {
-	method reflect.Method
-	args   reflect.Type
-	reply  reflect.Type
+	path     string
+	method   reflect.Method
+	args     reflect.Type
+	reply    reflect.Type
+	readOnly bool
+	coalesce bool
+	noArgs   bool
+	returns  bool
+
+	pathFields map[string][]int // 路径参数名到args字段的索引
+
+	rawArgs     bool
+	files       []jsonfield.Field
+	streamReply bool
+	bodyLimit   int64
+	panics      panicState
+
+	invoke    invoker
+	flatArgs  bool
+	argsPool  sync.Pool
+	replyPool sync.Pool
 }
 
 func parseMethod(m reflect.Method) (*method, error) {
-	_, _, args, reply, err := checkIns(m)
+	meth, err := newMethod("method "+m.Name, m.Type, 1)
 	if err != nil {
 		return nil, err
 	}
-	if err = checkOuts(m); err != nil {
+	meth.method = m
+	return meth, nil
+}
+
+// parseFunc 解析使用RegisterFunc注册的函数
+func parseFunc(path string, fn interface{}) (*method, error) {
+	val := reflect.ValueOf(fn)
+	if val.Kind() != reflect.Func || val.IsNil() {
+		return nil, fmt.Errorf("function %s is not a non-nil func: %T", path, fn)
+	}
+	meth, err := newMethod("function "+path, val.Type(), 0)
+	if err != nil {
+		return nil, err
+	}
+	meth.path = path
+	meth.invoke = newInvoker(val, meth)
+	return meth, nil
+}
+
+func newMethod(desc string, ftype reflect.Type, skip int) (*method, error) {
+	sig, err := checkSignature(desc, ftype, skip)
+	if err != nil {
 		return nil, err
 	}
-	return &method{method: m, args: args, reply: reply}, nil
+	flatArgs := sig.args.Kind() != reflect.Ptr && !isNilInterface(sig.args) && isFlat(sig.args)
+	return &method{
+		args:     sig.args,
+		reply:    sig.reply,
+		noArgs:   sig.noArgs,
+		returns:  sig.returns,
+		flatArgs: flatArgs,
+
+		rawArgs:     isRawArgs(sig.args),
+		files:       fileFields(sig.args),
+		streamReply: isStreamReply(sig.reply),
+	}, nil
+}
+
+// 约定接口中的方法, 不作为rpc方法
+var conventionMethods = map[string]bool{
+	"ReadOnlyMethods": true,
 }
 
 func suitableMethods(typ reflect.Type, reportErr bool) map[string]*method {
 	methods := make(map[string]*method)
 	for i := 0; i < typ.NumMethod(); i++ {
 		m := typ.Method(i)
-		if m.PkgPath != "" {
+		if m.PkgPath != "" || conventionMethods[m.Name] {
 			continue
 		}
 		meth, err := parseMethod(m)
 		if err != nil {
 			if reportErr {
-				log.Printf("parse method: %v", err)
+				Logger().Warnf("parse method: %v", err)
 			}
 			continue
 		}
@@ -114,6 +247,7 @@ type class struct {
 	name    string
 	rcvr    reflect.Value
 	methods map[string]*method
+	funcs   bool // 由RegisterFunc注册的函数组成, 没有接收者
 }
 
 func parseClass(name string, rcvr reflect.Value) (*class, error) {
@@ -129,6 +263,10 @@ func parseClass(name string, rcvr reflect.Value) (*class, error) {
 		}
 		return nil, errors.New(str)
 	}
+	for mname, m := range methods {
+		m.path = joinPath(name, mname)
+		m.invoke = newInvoker(rcvr.Method(m.method.Index), m)
+	}
 	return &class{name: name, rcvr: rcvr, methods: methods}, nil
 }
 
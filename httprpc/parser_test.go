@@ -138,6 +138,72 @@ func (ill) Test31(context.Context, int, int) {
 func (ill) Test32(context.Context, int, *a) {
 }
 
+type shapes struct{}
+
+func (shapes) NoArgs(context.Context, *int) error {
+	return nil
+}
+
+func (shapes) Returns(context.Context, A) (*A, error) {
+	return nil, nil
+}
+
+func (shapes) NoArgsReturns(context.Context) (int, error) {
+	return 0, nil
+}
+
+func (shapes) IllOnlyContext(context.Context) error {
+	return nil
+}
+
+func (shapes) IllReturns(context.Context, int, *int) (int, error) {
+	return 0, nil
+}
+
+func (shapes) IllSecondOut(context.Context, int) (int, bool) {
+	return 0, false
+}
+
+func (shapes) IllReply(context.Context, int) (a, error) {
+	return a{}, nil
+}
+
+func TestCheckSignature(t *testing.T) {
+	typ := reflect.TypeOf(shapes{})
+	tests := []struct {
+		name    string
+		args    reflect.Type
+		reply   reflect.Type
+		noArgs  bool
+		returns bool
+	}{
+		{name: "NoArgs", args: typeOfNilInterface, reply: reflect.TypeOf((*int)(nil)), noArgs: true},
+		{name: "Returns", args: reflect.TypeOf(A{}), reply: reflect.TypeOf((**A)(nil)), returns: true},
+		{name: "NoArgsReturns", args: typeOfNilInterface, reply: reflect.TypeOf((*int)(nil)), noArgs: true, returns: true},
+		{name: "IllOnlyContext"},
+		{name: "IllReturns"},
+		{name: "IllSecondOut"},
+		{name: "IllReply"},
+	}
+	for _, tt := range tests {
+		m, _ := typ.MethodByName(tt.name)
+		sig, err := checkSignature("method "+m.Name, m.Type, 1)
+		if tt.args == nil {
+			if err == nil {
+				t.Fatalf("%s: checkSignature return nil error", tt.name)
+			}
+			t.Logf("checkSignature: %v", err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: checkSignature: %v", tt.name, err)
+		}
+		if got, want := sig, (signature{args: tt.args, reply: tt.reply, noArgs: tt.noArgs, returns: tt.returns}); got != want {
+			t.Fatalf("%s: signature: %+v != %+v", tt.name, got, want)
+		}
+	}
+}
+
 func TestCheckInsCorrect(t *testing.T) {
 	var a correct
 	typ := reflect.TypeOf(a)
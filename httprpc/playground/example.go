@@ -0,0 +1,112 @@
+package playground
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/internal/jsonfield"
+)
+
+var (
+	typeOfTime          = reflect.TypeOf(time.Time{})
+	typeOfRawMessage    = reflect.TypeOf(json.RawMessage{})
+	typeOfJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	typeOfTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// object 按字段顺序编码的JSON对象
+type object struct {
+	names  []string
+	values []interface{}
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range o.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(name)
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Example 按类型生成示例参数: 基本类型为零值, 切片和map包含一个元素,
+// 结构体按JSON字段顺序展开, 递归类型第二次出现时为null.
+func Example(t reflect.Type) json.RawMessage {
+	b, err := json.MarshalIndent(example(t, make(map[reflect.Type]bool)), "", "  ")
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return b
+}
+
+func example(t reflect.Type, seen map[reflect.Type]bool) interface{} {
+	switch {
+	case t.Kind() == reflect.Interface:
+		return nil
+	case t == typeOfTime:
+		return time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	case t == typeOfRawMessage:
+		return json.RawMessage("{}")
+	case t.Kind() != reflect.Ptr && (t.Implements(typeOfJSONMarshaler) || reflect.PtrTo(t).Implements(typeOfJSONMarshaler)):
+		return nil
+	case t.Kind() != reflect.Ptr && (t.Implements(typeOfTextMarshaler) || reflect.PtrTo(t).Implements(typeOfTextMarshaler)):
+		return ""
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return example(t.Elem(), seen)
+	case reflect.Bool:
+		return false
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return 0
+	case reflect.String:
+		return ""
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ""
+		}
+		return []interface{}{example(t.Elem(), seen)}
+	case reflect.Array:
+		list := make([]interface{}, t.Len())
+		for i := range list {
+			list[i] = example(t.Elem(), seen)
+		}
+		return list
+	case reflect.Map:
+		key := "key"
+		if t.Key().Kind() != reflect.String {
+			key = "0"
+		}
+		return map[string]interface{}{key: example(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return nil
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		o := &object{}
+		for _, f := range jsonfield.Fields(t) {
+			o.names = append(o.names, f.Name)
+			o.values = append(o.values, example(f.Type, seen))
+		}
+		return o
+	}
+	return nil
+}
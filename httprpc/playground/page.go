@@ -0,0 +1,185 @@
+package playground
+
+// pageHTML 调试页面, 样式和脚本都内联在页面中
+const pageHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+* { box-sizing: border-box; }
+body { margin: 0; font: 14px/1.4 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; display: flex; height: 100vh; }
+nav { width: 300px; border-right: 1px solid #ddd; overflow-y: auto; background: #fafafa; }
+nav input { width: calc(100% - 16px); margin: 8px; padding: 6px; }
+nav h3 { margin: 12px 8px 4px; font-size: 12px; color: #888; text-transform: uppercase; }
+nav a { display: block; padding: 4px 16px; color: #222; text-decoration: none; cursor: pointer; word-break: break-all; }
+nav a:hover, nav a.active { background: #e6f0ff; }
+nav a.deprecated { text-decoration: line-through; color: #999; }
+.tag { font-size: 11px; padding: 0 4px; border-radius: 3px; background: #ddd; margin-left: 4px; }
+main { flex: 1; padding: 16px; overflow-y: auto; }
+h1 { font-size: 18px; margin: 0 0 12px; }
+#path { font-family: monospace; font-size: 16px; margin-bottom: 12px; }
+.row { display: flex; gap: 8px; margin-bottom: 8px; align-items: center; }
+.row label { width: 110px; color: #555; }
+.row input[type=text] { flex: 1; padding: 4px 6px; font-family: monospace; }
+textarea { width: 100%; font-family: monospace; font-size: 13px; padding: 6px; }
+button { padding: 6px 20px; font-size: 14px; cursor: pointer; }
+#status { margin: 12px 0 6px; font-weight: bold; }
+#status.error { color: #c00; }
+#status.ok { color: #080; }
+table.err { border-collapse: collapse; margin-bottom: 8px; }
+table.err td { border: 1px solid #eee; padding: 4px 8px; vertical-align: top; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; white-space: pre-wrap; word-break: break-all; }
+</style>
+</head>
+<body>
+<nav>
+<input id="filter" type="text" placeholder="filter methods">
+<div id="methods"></div>
+</nav>
+<main>
+<h1>{{.Title}}</h1>
+<div id="path">select a method</div>
+<div id="params"></div>
+<div class="row"><label>X-Trace-Id</label><input id="traceId" type="text" placeholder="generated by server if empty"></div>
+<div class="row"><label>X-Client-Id</label><input id="clientId" type="text"></div>
+<div class="row"><label>X-Verbose</label><input id="verbose" type="checkbox"></div>
+<div class="row"><label>Headers</label><textarea id="headers" rows="2" placeholder="Name: value, one per line"></textarea></div>
+<div class="row"><label>Args</label><textarea id="args" rows="14"></textarea></div>
+<button id="send" disabled>Send</button>
+<div id="status"></div>
+<div id="error"></div>
+<pre id="reply" hidden></pre>
+</main>
+<script>
+(function() {
+	var endpoint = {{.Endpoint}};
+	var methods = [], current = null;
+	var $ = function(id) { return document.getElementById(id); };
+
+	function text(tag, s, cls) {
+		var e = document.createElement(tag);
+		e.textContent = s;
+		if (cls) e.className = cls;
+		return e;
+	}
+
+	function render() {
+		var filter = $("filter").value.toLowerCase();
+		var list = $("methods"), cls = null;
+		list.innerHTML = "";
+		methods.forEach(function(m) {
+			if (filter && m.Path.toLowerCase().indexOf(filter) < 0) return;
+			if (m.Class !== cls) {
+				cls = m.Class;
+				list.appendChild(text("h3", cls));
+			}
+			var a = text("a", m.Name, m.Deprecated ? "deprecated" : "");
+			if (m.ReadOnly) a.appendChild(text("span", "GET", "tag"));
+			if (m.Alias) a.appendChild(text("span", "alias", "tag"));
+			if (current && current.Path === m.Path) a.className += " active";
+			a.onclick = function() { select(m); };
+			list.appendChild(a);
+		});
+	}
+
+	function select(m) {
+		current = m;
+		$("path").textContent = "POST " + m.Path + (m.Alias ? "  (alias of " + m.Alias + ")" : "");
+		var params = $("params");
+		params.innerHTML = "";
+		(m.PathParams || []).forEach(function(p) {
+			var row = document.createElement("div"), input = document.createElement("input");
+			row.className = "row";
+			row.appendChild(text("label", "{" + p + "}"));
+			input.type = "text";
+			input.dataset.param = p;
+			row.appendChild(input);
+			params.appendChild(row);
+		});
+		$("args").value = m.Args === null ? "" : JSON.stringify(m.Args, null, 2);
+		$("send").disabled = false;
+		render();
+	}
+
+	function headers() {
+		var h = {"Content-Type": "application/json"};
+		if ($("traceId").value) h["X-Trace-Id"] = $("traceId").value;
+		if ($("clientId").value) h["X-Client-Id"] = $("clientId").value;
+		if ($("verbose").checked) h["X-Verbose"] = "1";
+		$("headers").value.split("\n").forEach(function(line) {
+			var i = line.indexOf(":");
+			if (i > 0) h[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+		});
+		return h;
+	}
+
+	function show(res, body, latency) {
+		var status = $("status"), errBox = $("error"), reply = $("reply");
+		var trace = res.headers.get("X-Trace-Id");
+		status.textContent = res.status + " " + res.statusText + " · " + latency.toFixed(1) + " ms" + (trace ? " · trace " + trace : "");
+		status.className = res.ok ? "ok" : "error";
+		errBox.innerHTML = "";
+		var data = null;
+		try { data = JSON.parse(body); } catch (e) {}
+		if (!res.ok && data && typeof data.Code === "number") {
+			var table = document.createElement("table");
+			table.className = "err";
+			["Code", "Error", "Cause", "Details", "Stack"].forEach(function(k) {
+				if (data[k] === undefined || data[k] === "") return;
+				var tr = document.createElement("tr");
+				tr.appendChild(text("td", k));
+				var v = typeof data[k] === "object" ? JSON.stringify(data[k], null, 2) : String(data[k]);
+				var td = document.createElement("td");
+				td.appendChild(text(k === "Stack" || k === "Details" ? "pre" : "span", v));
+				tr.appendChild(td);
+				table.appendChild(tr);
+			});
+			errBox.appendChild(table);
+		}
+		reply.hidden = false;
+		reply.textContent = data !== null ? JSON.stringify(data, null, 2) : body;
+	}
+
+	// path 将路径模板中的{name}替换为输入的参数值
+	function path() {
+		var p = current.Path, missing = null;
+		Array.prototype.forEach.call($("params").querySelectorAll("input"), function(input) {
+			if (!input.value) missing = missing || input.dataset.param;
+			p = p.replace("{" + input.dataset.param + "}", encodeURIComponent(input.value));
+		});
+		return missing ? {missing: missing} : {path: p};
+	}
+
+	function send() {
+		if (!current) return;
+		var p = path();
+		if (p.missing) {
+			$("status").textContent = "path param " + p.missing + " is required";
+			$("status").className = "error";
+			return;
+		}
+		var start = performance.now();
+		$("send").disabled = true;
+		fetch(endpoint + p.path, {method: "POST", headers: headers(), body: $("args").value}).then(function(res) {
+			return res.text().then(function(body) { show(res, body, performance.now() - start); });
+		}).catch(function(err) {
+			$("status").textContent = "request failed: " + err;
+			$("status").className = "error";
+		}).then(function() { $("send").disabled = false; });
+	}
+
+	$("filter").oninput = render;
+	$("send").onclick = send;
+	$("args").onkeydown = function(e) {
+		if ((e.ctrlKey || e.metaKey) && e.key === "Enter") send();
+	};
+	fetch(location.pathname + "?methods").then(function(res) { return res.json(); }).then(function(list) {
+		methods = list || [];
+		render();
+	});
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,72 @@
+// Package playground 提供在浏览器中浏览和调用httprpc方法的调试页面.
+package playground
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+)
+
+// Options 调试页面选项
+type Options struct {
+	Title    string // 页面标题, 为空时为httprpc playground
+	Endpoint string // 方法调用地址的前缀, 如http://localhost:8000, 为空时与页面同源
+}
+
+// Method 页面中的方法
+type Method struct {
+	Path       string
+	Class      string
+	Name       string
+	ReadOnly   bool            `json:",omitempty"`
+	Alias      string          `json:",omitempty"`
+	Deprecated bool            `json:",omitempty"`
+	PathParams []string        `json:",omitempty"` // 调用时替换Path中的{name}
+	Args       json.RawMessage // 示例参数
+}
+
+// Methods 返回Server中已注册的方法及其示例参数
+func Methods(s *httprpc.Server) []Method {
+	infos := s.Methods()
+	methods := make([]Method, 0, len(infos))
+	for _, m := range infos {
+		methods = append(methods, Method{
+			Path:       m.Path,
+			Class:      m.Class,
+			Name:       m.Name,
+			ReadOnly:   m.ReadOnly,
+			Alias:      m.Alias,
+			Deprecated: m.Deprecated,
+			PathParams: m.PathParams,
+			Args:       Example(m.Args),
+		})
+	}
+	return methods
+}
+
+type pageData struct {
+	Title    string
+	Endpoint string
+}
+
+// Handler 返回调试页面的http.Handler, 页面不依赖外部资源.
+// 带methods查询参数的请求返回JSON格式的方法列表, 每次请求时根据Server的当前状态生成.
+func Handler(s *httprpc.Server, opts Options) http.Handler {
+	data := pageData{Title: opts.Title, Endpoint: opts.Endpoint}
+	if data.Title == "" {
+		data.Title = "httprpc playground"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["methods"]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(Methods(s))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page.Execute(w, data)
+	})
+}
+
+var page = template.Must(template.New("playground").Parse(pageHTML))
@@ -0,0 +1,135 @@
+package playground
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+)
+
+type Args struct {
+	Name    string            `json:"name"`
+	Tags    []string          `json:"tags,omitempty"`
+	Labels  map[string]int    `json:"labels"`
+	Counts  map[int]bool      `json:"counts"`
+	Created time.Time         `json:"created"`
+	Next    *Args             `json:"next"`
+	Raw     json.RawMessage   `json:"raw"`
+	Skip    int               `json:"-"`
+	Extra   map[string]string `json:"-"`
+}
+
+type Reply struct{}
+
+type Device int
+
+func (d *Device) Get(ctx context.Context, args *Args, reply *Reply) error {
+	return nil
+}
+
+func (d *Device) Ping(ctx context.Context, args interface{}, reply interface{}) error {
+	return nil
+}
+
+func TestExample(t *testing.T) {
+	tests := []struct {
+		typ    reflect.Type
+		expect string
+	}{
+		{typ: reflect.TypeOf(0), expect: `0`},
+		{typ: reflect.TypeOf(""), expect: `""`},
+		{typ: reflect.TypeOf([]int{}), expect: `[0]`},
+		{typ: reflect.TypeOf([2]bool{}), expect: `[false,false]`},
+		{typ: reflect.TypeOf((*interface{})(nil)).Elem(), expect: `null`},
+		{
+			typ:    reflect.TypeOf(&Args{}),
+			expect: `{"name":"","tags":[""],"labels":{"key":0},"counts":{"0":false},"created":"2006-01-02T15:04:05Z","next":null,"raw":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		var v interface{}
+		if err := json.Unmarshal(Example(tt.typ), &v); err != nil {
+			t.Fatalf("%v: unmarshal: %v", tt.typ, err)
+		}
+		got, _ := json.Marshal(v)
+		// 解码后比较, 与对象的字段顺序无关
+		var want interface{}
+		json.Unmarshal([]byte(tt.expect), &want)
+		if !reflect.DeepEqual(v, want) {
+			t.Errorf("%v: got %s, want %s", tt.typ, got, tt.expect)
+		}
+	}
+
+	// 示例参数可以被解码回原类型, 且字段顺序与结构体一致
+	b := Example(reflect.TypeOf(Args{}))
+	var args Args
+	if err := json.Unmarshal(b, &args); err != nil {
+		t.Fatalf("unmarshal example: %v", err)
+	}
+	if i, j := strings.Index(string(b), `"name"`), strings.Index(string(b), `"created"`); i < 0 || i > j {
+		t.Errorf("field order: %s", b)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var d Device
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/device/v1", &d); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	h := Handler(s, Options{Title: "device <v1>"})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/playground/?methods", nil))
+	var methods []Method
+	if err := json.Unmarshal(w.Body.Bytes(), &methods); err != nil {
+		t.Fatalf("decode methods: %v", err)
+	}
+	if got, want := len(methods), 2; got != want {
+		t.Fatalf("methods: got %d, want %d", got, want)
+	}
+	if got, want := methods[0].Path, "/device/v1/Get"; got != want {
+		t.Errorf("path: got %s, want %s", got, want)
+	}
+	if got, want := string(methods[1].Args), "null"; got != want {
+		t.Errorf("Ping args: got %s, want %s", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/playground/", nil))
+	if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("Content-Type: got %s, want %s", got, want)
+	}
+	page := w.Body.String()
+	if !strings.Contains(page, "<title>device &lt;v1&gt;</title>") {
+		t.Errorf("title not escaped")
+	}
+	for _, external := range []string{"src=", "href=", "@import"} {
+		if strings.Contains(page, external) {
+			t.Errorf("page references external asset: %s", external)
+		}
+	}
+	if !strings.Contains(page, `var endpoint = "";`) {
+		t.Errorf("endpoint not rendered")
+	}
+}
+
+func TestMethodsPathParams(t *testing.T) {
+	var d Device
+	s := httprpc.NewServer(nil)
+	if err := s.Register("/devices/{id}", &d); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	methods := Methods(s)
+	if got, want := len(methods), 2; got != want {
+		t.Fatalf("methods: got %d, want %d", got, want)
+	}
+	if got, want := methods[0].PathParams, []string{"id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("path params: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package httprpc
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/internal/jsonfield"
+)
+
+// 非结构体类型的参数从该查询参数中读取
+const queryArgs = "args"
+
+var typeOfTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// bindQuery 将URL查询参数绑定到v.
+//
+// 结构体字段按json tag或字段名匹配, 嵌套结构体使用"a.b"形式,
+// 基本类型的切片使用重复的参数"a=1&a=2", 结构体切片使用"a.0.b"形式, 下标小于不同下标的个数,
+// map[string]T使用"a.key"形式.
+func bindQuery(q url.Values, v reflect.Value) error {
+	if isScalarType(v.Type()) || isScalarSlice(v.Type()) {
+		return bindValue(v, queryArgs, q)
+	}
+	return bindValue(v, "", q)
+}
+
+func isScalarType(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(typeOfTextUnmarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func isScalarSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && isScalarType(t.Elem())
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func hasKeyPrefix(q url.Values, key string) bool {
+	if key == "" {
+		return true
+	}
+	for k := range q {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// groupKeys 将以prefix.开头的参数按下一级名字分组, 每组只包含该名字下的参数,
+// 绑定元素时只需查找所在的组.
+func groupKeys(q url.Values, prefix string) map[string]url.Values {
+	groups := make(map[string]url.Values)
+	for k, values := range q {
+		if !strings.HasPrefix(k, prefix+".") {
+			continue
+		}
+		sub := k[len(prefix)+1:]
+		if i := strings.Index(sub, "."); i >= 0 {
+			sub = sub[:i]
+		}
+		g, ok := groups[sub]
+		if !ok {
+			g = make(url.Values)
+			groups[sub] = g
+		}
+		g[k] = values
+	}
+	return groups
+}
+
+func bindValue(v reflect.Value, key string, q url.Values) error {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		if !hasKeyPrefix(q, key) {
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return bindValue(v.Elem(), key, q)
+	}
+
+	if isScalarType(t) {
+		values, ok := q[key]
+		if !ok || len(values) <= 0 {
+			return nil
+		}
+		return setScalar(v, key, values[0])
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range jsonfield.Fields(t) {
+			if err := bindValue(f.Value(v), joinKey(key, f.Name), q); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if isScalarType(t.Elem()) {
+			values := q[key]
+			if len(values) <= 0 {
+				return nil
+			}
+			s := reflect.MakeSlice(t, len(values), len(values))
+			for i, value := range values {
+				if err := setScalar(s.Index(i), key, value); err != nil {
+					return err
+				}
+			}
+			v.Set(s)
+			return nil
+		}
+		// 下标不能超过不同下标的个数, 避免按客户端给出的下标分配过大的切片
+		groups := groupKeys(q, key)
+		n := 0
+		for sub := range groups {
+			i, err := strconv.Atoi(sub)
+			if err != nil || i < 0 || i >= len(groups) || strconv.Itoa(i) != sub {
+				return fmt.Errorf("%s: invalid slice index %q", key, sub)
+			}
+			if i+1 > n {
+				n = i + 1
+			}
+		}
+		if n <= 0 {
+			return nil
+		}
+		s := reflect.MakeSlice(t, n, n)
+		for i := 0; i < n; i++ {
+			if err := bindValue(s.Index(i), joinKey(key, strconv.Itoa(i)), groups[strconv.Itoa(i)]); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: unsupported map key type %s", key, t.Key())
+		}
+		groups := groupKeys(q, key)
+		if len(groups) <= 0 {
+			return nil
+		}
+		subs := make([]string, 0, len(groups))
+		for sub := range groups {
+			subs = append(subs, sub)
+		}
+		sort.Strings(subs)
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		for _, sub := range subs {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := bindValue(elem, joinKey(key, sub), groups[sub]); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(sub).Convert(t.Key()), elem)
+		}
+		return nil
+
+	case reflect.Interface:
+		if values, ok := q[key]; ok && len(values) > 0 && t.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(values[0]))
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: unsupported type %s", key, t)
+}
+
+func setScalar(v reflect.Value, key, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		return nil
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			v.SetBool(b)
+		}
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, v.Type().Bits()); err == nil {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, v.Type().Bits()); err == nil {
+			v.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, v.Type().Bits()); err == nil {
+			v.SetFloat(f)
+		}
+	default:
+		err = fmt.Errorf("unsupported type %s", v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package httprpc
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type QueryInner struct {
+	Name string `json:"name"`
+	Age  int
+}
+
+type QueryArgs struct {
+	QueryInner
+	ID     int64             `json:"id"`
+	Ok     bool              `json:"ok,omitempty"`
+	Tags   []string          `json:"tags"`
+	Inner  *QueryInner       `json:"inner"`
+	Items  []QueryInner      `json:"items"`
+	Labels map[string]string `json:"labels"`
+	Time   time.Time         `json:"time"`
+	Skip   string            `json:"-"`
+}
+
+func TestBindQuery(t *testing.T) {
+	q, err := url.ParseQuery("name=n&Age=3&id=1&ok=true&tags=a&tags=b&inner.name=x&items.1.Age=2&items.0.name=y&labels.a=1&time=2020-01-02T03:04:05Z&Skip=s")
+	if err != nil {
+		t.Fatalf("parse query: %v", err)
+	}
+	var args QueryArgs
+	if err = bindQuery(q, reflect.ValueOf(&args).Elem()); err != nil {
+		t.Fatalf("bindQuery: %v", err)
+	}
+	want := QueryArgs{
+		QueryInner: QueryInner{Name: "n", Age: 3},
+		ID:         1,
+		Ok:         true,
+		Tags:       []string{"a", "b"},
+		Inner:      &QueryInner{Name: "x"},
+		Items:      []QueryInner{{Name: "y"}, {Age: 2}},
+		Labels:     map[string]string{"a": "1"},
+		Time:       time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("bindQuery: got %+v, want %+v", args, want)
+	}
+}
+
+func TestBindQueryScalar(t *testing.T) {
+	var s string
+	if err := bindQuery(url.Values{"args": {"hello"}}, reflect.ValueOf(&s).Elem()); err != nil {
+		t.Fatalf("bindQuery: %v", err)
+	}
+	if got, want := s, "hello"; got != want {
+		t.Fatalf("bindQuery: got %v, want %v", got, want)
+	}
+}
+
+func TestBindQueryError(t *testing.T) {
+	tests := []string{
+		"id=x",
+		"ok=x",
+		"items.x.name=y",
+		"items.999999999.name=y",
+		"items.1.name=y",
+		"items.0.name=x&items.2.name=y",
+		"items.00.name=x&items.1.name=y",
+		// 其它参数不能放宽下标的上限
+		"items.5.name=y&a=1&b=2&c=3&d=4&e=5&f=6",
+		"time=now",
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt)
+		var args QueryArgs
+		if err := bindQuery(q, reflect.ValueOf(&args).Elem()); err == nil {
+			t.Errorf("%s: bindQuery return error is nil", tt)
+		} else {
+			t.Logf("%s: bindQuery: %v", tt, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Record 一次调用的录制结果, Args/Reply为编码后的请求和响应(或errReply).
+// Truncated为true时请求体或响应体超过录制上限, Args和Reply都不录制.
+type Record struct {
+	Time      time.Time
+	Method    string
+	Path      string
+	Query     string `json:",omitempty"`
+	Header    http.Header
+	Args      json.RawMessage `json:",omitempty"`
+	Status    int
+	Reply     json.RawMessage `json:",omitempty"`
+	Truncated bool            `json:",omitempty"`
+	Latency   time.Duration
+}
+
+// DefaultRedactHeaders 默认不录制的请求头
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// DefaultRecordBodySize 默认的请求体和响应体录制上限
+const DefaultRecordBodySize = 64 << 10
+
+// Recorder 录制中间件, 每次调用以一行JSON的形式写入Record.
+// io.Reader参数、流式响应和multipart上传的调用不录制.
+type Recorder struct {
+	RedactHeaders []string // 不录制的请求头, 如认证信息
+	MaxBodySize   int      // 请求体和响应体的录制上限, 小于等于0时不限制
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder 创建录制中间件, 使用DefaultRedactHeaders和DefaultRecordBodySize.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		RedactHeaders: DefaultRedactHeaders,
+		MaxBodySize:   DefaultRecordBodySize,
+		enc:           json.NewEncoder(w),
+	}
+}
+
+func (p *Recorder) ServeHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request, next NextMiddleware) error {
+	if rctx, ok := ctx.(*Context); ok && !recordable(rctx.method, r) {
+		return next(ctx, w, r)
+	}
+	args, truncated, err := p.readBody(r)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	c := newResponseCapture(w)
+	c.limit = p.MaxBodySize
+	err = next(ctx, c, r)
+	header := r.Header.Clone()
+	for _, key := range p.RedactHeaders {
+		header.Del(key)
+	}
+	rec := Record{
+		Time:      start,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Header:    header,
+		Status:    c.status,
+		Truncated: truncated || c.truncated,
+		Latency:   time.Since(start),
+	}
+	if !rec.Truncated {
+		rec.Args, rec.Reply = rawJSON(args), rawJSON(c.body.Bytes())
+	}
+	if err != nil {
+		code, er := newErrReply(err, false)
+		rec.Status = code.Status()
+		rec.Reply, _ = json.Marshal(er)
+	}
+	p.write(&rec)
+	return err
+}
+
+func recordable(meth *method, r *http.Request) bool {
+	if meth == nil {
+		return true
+	}
+	return !meth.rawArgs && !meth.streamReply && !(meth.files != nil && isMultipart(r.Header))
+}
+
+// readBody 读取不超过MaxBodySize的请求体, 超过时truncated为true, 请求体仍可被完整读取.
+func (p *Recorder) readBody(r *http.Request) (body []byte, truncated bool, err error) {
+	var buf bytes.Buffer
+	var src io.Reader = r.Body
+	if p.MaxBodySize > 0 {
+		src = io.LimitReader(r.Body, int64(p.MaxBodySize)+1)
+	}
+	if _, err = buf.ReadFrom(src); err != nil {
+		return nil, false, bodyError(err)
+	}
+	if p.MaxBodySize > 0 && buf.Len() > p.MaxBodySize {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(&buf, r.Body), r.Body}
+		return nil, true, nil
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(buf.Bytes()))
+	return buf.Bytes(), false, nil
+}
+
+func (p *Recorder) write(rec *Record) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.enc.Encode(rec); err != nil {
+		Logger().Errorf("write record %s: %v", rec.Path, err)
+	}
+}
+
+func rawJSON(b []byte) json.RawMessage {
+	b = bytes.TrimSpace(b)
+	if len(b) <= 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	data, _ := json.Marshal(string(b))
+	return json.RawMessage(data)
+}
+
+type responseCapture struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	limit     int // 大于0时body最多保存limit字节, 超过时丢弃并设置truncated
+	truncated bool
+}
+
+func newResponseCapture(w http.ResponseWriter) *responseCapture {
+	return &responseCapture{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+// Flush 转发给原ResponseWriter, 使流式响应可以及时发送
+func (c *responseCapture) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.limit > 0 && c.body.Len()+len(b) > c.limit {
+		c.truncated = true
+		c.body.Reset()
+	}
+	if !c.truncated {
+		c.body.Write(b)
+	}
+	return c.ResponseWriter.Write(b)
+}
@@ -0,0 +1,115 @@
+package httprpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+func TestRecorder(t *testing.T) {
+	var a Arith
+	var buf bytes.Buffer
+	s := NewServer(nil)
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	s.AddMiddleware(NewRecorder(&buf))
+
+	var reply Reply
+	if err := callTestServer(s, "/arith/Add", Args{A: 1, B: 2}, &reply); err != nil {
+		t.Fatalf("callTestServer: %v", err)
+	}
+	if err := callTestServer(s, "/arith/Div", Args{A: 1, B: 0}, &reply); err == nil {
+		t.Fatalf("callTestServer return error is nil")
+	}
+
+	dec := json.NewDecoder(&buf)
+	tests := []struct {
+		path   string
+		status int
+		reply  string
+	}{
+		{path: "/arith/Add", status: http.StatusOK, reply: `{"C":3}`},
+		{path: "/arith/Div", status: codes.Unknown.Status()},
+	}
+	for i, tt := range tests {
+		var rec Record
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("case%d: decode: %v", i, err)
+		}
+		if got, want := rec.Path, tt.path; got != want {
+			t.Errorf("case%d: path: got %v, want %v", i, got, want)
+		}
+		if got, want := rec.Status, tt.status; got != want {
+			t.Errorf("case%d: status: got %v, want %v", i, got, want)
+		}
+		if tt.reply != "" {
+			if got, want := string(rec.Reply), tt.reply; got != want {
+				t.Errorf("case%d: reply: got %v, want %v", i, got, want)
+			}
+		}
+		t.Logf("case%d: record: %+v", i, rec)
+	}
+}
+
+func TestRecorderLimits(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer(nil)
+	if err := s.Register("/arith", new(Arith)); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/firmware", Firmware{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rec := NewRecorder(&buf)
+	rec.MaxBodySize = 16
+	s.AddMiddleware(rec)
+
+	call := func(path, contentType, body string) int {
+		r := httptest.NewRequest("POST", path, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Authorization", "Bearer secret")
+		r.Header.Set("Cookie", "session=secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := call("/arith/Add", "application/json", `{"A":1,"B":2}`); code != http.StatusOK {
+		t.Fatalf("Add: status %d", code)
+	}
+	if code := call("/arith/Add", "application/json", `{"A":1, "B":2, "Ignored":"0123456789"}`); code != http.StatusOK {
+		t.Fatalf("Add large: status %d", code)
+	}
+	if code := call("/firmware/Upload", "application/octet-stream", "firmware"); code != http.StatusOK {
+		t.Fatalf("Upload: status %d", code)
+	}
+
+	var records []Record
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r Record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		records = append(records, r)
+	}
+	if got, want := len(records), 2; got != want {
+		t.Fatalf("records: got %d, want %d", got, want)
+	}
+	for i, r := range records {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" {
+			t.Errorf("record%d: sensitive headers recorded: %v", i, r.Header)
+		}
+	}
+	if r := records[0]; r.Truncated || string(r.Args) != `{"A":1,"B":2}` {
+		t.Errorf("record0: %+v", r)
+	}
+	if r := records[1]; !r.Truncated || r.Args != nil || r.Reply != nil {
+		t.Errorf("record1: %+v", r)
+	}
+}
@@ -0,0 +1,279 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+)
+
+// ReadRecords 读取httprpc.Recorder录制的JSON行文件
+func ReadRecords(r io.Reader) ([]httprpc.Record, error) {
+	var records []httprpc.Record
+	dec := json.NewDecoder(r)
+	for {
+		var rec httprpc.Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, fmt.Errorf("decode record %d: %v", len(records), err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// Rule 忽略规则, 格式为 [<path pattern>:]<field path>.
+//
+// path pattern 使用path.Match语法匹配调用路径, 为空则匹配所有路径;
+// field path 以'.'分隔, '*'匹配任意一个字段名或数组下标.
+// 例如: "*.UpdatedAt", "/device/v1/Get:Items.*.Time".
+type Rule struct {
+	Path  string
+	Field []string
+}
+
+func ParseRule(s string) (Rule, error) {
+	var r Rule
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		r.Path, s = s[:i], s[i+1:]
+		if _, err := path.Match(r.Path, ""); err != nil {
+			return r, fmt.Errorf("invalid path pattern %q: %v", r.Path, err)
+		}
+	}
+	if s == "" {
+		return r, fmt.Errorf("empty field path")
+	}
+	r.Field = strings.Split(s, ".")
+	return r, nil
+}
+
+func (r Rule) matchPath(p string) bool {
+	if r.Path == "" {
+		return true
+	}
+	ok, _ := path.Match(r.Path, p)
+	return ok
+}
+
+func (r Rule) matchField(field []string) bool {
+	if len(field) != len(r.Field) {
+		return false
+	}
+	for i, f := range r.Field {
+		if f != "*" && f != field[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Result 一次回放的结果
+type Result struct {
+	Record  httprpc.Record
+	Status  int
+	Reply   json.RawMessage
+	Latency time.Duration
+	Diffs   []string
+	Err     error
+}
+
+func (r *Result) OK() bool {
+	return r.Err == nil && len(r.Diffs) <= 0
+}
+
+// Replayer 将录制的调用发送到URL指向的服务并比较响应
+type Replayer struct {
+	URL    string
+	Client *http.Client
+	Rules  []Rule
+}
+
+func (p *Replayer) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return &httprpc.HTTPClient
+}
+
+func (p *Replayer) Replay(ctx context.Context, rec httprpc.Record) (res Result) {
+	res.Record = rec
+	if rec.Truncated {
+		res.Err = errors.New("record truncated, args or reply not recorded")
+		return res
+	}
+	method := rec.Method
+	if method == "" {
+		method = "POST"
+	}
+	url := p.URL + rec.Path
+	if rec.Query != "" {
+		url += "?" + rec.Query
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(rec.Args))
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	req = req.WithContext(ctx)
+	for key, values := range rec.Header {
+		if key == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	start := time.Now()
+	resp, err := p.client().Do(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Latency = time.Since(start)
+	res.Status = resp.StatusCode
+	res.Reply = json.RawMessage(bytes.TrimSpace(body))
+
+	if res.Status != rec.Status {
+		res.Diffs = append(res.Diffs, fmt.Sprintf("status: want %d, got %d", rec.Status, res.Status))
+	}
+	var ignore []Rule
+	for _, r := range p.Rules {
+		if r.matchPath(rec.Path) {
+			ignore = append(ignore, r)
+		}
+	}
+	diffs, err := Diff(rec.Reply, res.Reply, ignore)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Diffs = append(res.Diffs, diffs...)
+	return res
+}
+
+func (p *Replayer) ReplayAll(ctx context.Context, records []httprpc.Record) []Result {
+	results := make([]Result, 0, len(records))
+	for _, rec := range records {
+		results = append(results, p.Replay(ctx, rec))
+	}
+	return results
+}
+
+// Diff 比较两个JSON文档, 返回不同之处, 命中rules的字段不参与比较.
+// rules的路径部分在这里不生效.
+func Diff(want, got json.RawMessage, rules []Rule) ([]string, error) {
+	w, err := decodeJSON(want)
+	if err != nil {
+		return nil, fmt.Errorf("decode want: %v", err)
+	}
+	g, err := decodeJSON(got)
+	if err != nil {
+		return nil, fmt.Errorf("decode got: %v", err)
+	}
+	var diffs []string
+	diff(&diffs, nil, w, g, rules)
+	return diffs, nil
+}
+
+func decodeJSON(data json.RawMessage) (interface{}, error) {
+	if len(bytes.TrimSpace(data)) <= 0 {
+		return nil, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func ignored(field []string, rules []Rule) bool {
+	for _, r := range rules {
+		if r.matchField(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func diff(diffs *[]string, field []string, want, got interface{}, rules []Rule) {
+	if len(field) > 0 && ignored(field, rules) {
+		return
+	}
+	name := strings.Join(field, ".")
+	if name == "" {
+		name = "."
+	}
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			diff(diffs, append(field[:len(field):len(field)], k), w[k], g[k], rules)
+		}
+		return
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			break
+		}
+		n := len(w)
+		if len(g) > n {
+			n = len(g)
+		}
+		for i := 0; i < n; i++ {
+			var wv, gv interface{}
+			if i < len(w) {
+				wv = w[i]
+			}
+			if i < len(g) {
+				gv = g[i]
+			}
+			diff(diffs, append(field[:len(field):len(field)], strconv.Itoa(i)), wv, gv, rules)
+		}
+		return
+	}
+	if !reflect.DeepEqual(want, got) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: want %s, got %s", name, jsonString(want), jsonString(got)))
+	}
+}
+
+func jsonString(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
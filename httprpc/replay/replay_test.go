@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc"
+)
+
+type Args struct {
+	A, B int
+}
+
+type Reply struct {
+	C    int
+	Time int64
+}
+
+type Arith struct {
+	offset int
+	now    int64
+}
+
+func (t *Arith) Add(ctx context.Context, args Args, reply *Reply) error {
+	t.now++
+	reply.C = args.A + args.B + t.offset
+	reply.Time = t.now
+	return nil
+}
+
+func mustParseRules(t *testing.T, ss ...string) []Rule {
+	var rules []Rule
+	for _, s := range ss {
+		r, err := ParseRule(s)
+		if err != nil {
+			t.Fatalf("ParseRule(%q): %v", s, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		want  string
+		got   string
+		rules []string
+		diffs int
+	}{
+		{want: `{"C":1}`, got: `{"C":1}`, diffs: 0},
+		{want: `{"C":1}`, got: `{"C":2}`, diffs: 1},
+		{want: `{"C":1,"T":1}`, got: `{"C":1,"T":2}`, rules: []string{"T"}, diffs: 0},
+		{want: `{"A":[{"T":1},{"T":2}]}`, got: `{"A":[{"T":3},{"T":4}]}`, rules: []string{"A.*.T"}, diffs: 0},
+		{want: `{"A":[1,2]}`, got: `{"A":[1]}`, diffs: 1},
+		{want: `{"A":1}`, got: `{"B":1}`, diffs: 2},
+		{want: `"a"`, got: `{"A":1}`, diffs: 1},
+	}
+	for i, tt := range tests {
+		diffs, err := Diff(json.RawMessage(tt.want), json.RawMessage(tt.got), mustParseRules(t, tt.rules...))
+		if err != nil {
+			t.Fatalf("case%d: Diff: %v", i, err)
+		}
+		if got, want := len(diffs), tt.diffs; got != want {
+			t.Errorf("case%d: diffs: got %v, want %v, %v", i, got, want, diffs)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule("/arith/*:Items.*.Time")
+	if err != nil {
+		t.Fatalf("ParseRule: %v", err)
+	}
+	if !r.matchPath("/arith/Add") || r.matchPath("/device/Add") {
+		t.Errorf("matchPath: %q", r.Path)
+	}
+	if !r.matchField([]string{"Items", "0", "Time"}) || r.matchField([]string{"Items", "Time"}) {
+		t.Errorf("matchField: %q", r.Field)
+	}
+	if _, err = ParseRule("/a:"); err == nil {
+		t.Errorf("ParseRule return error is nil")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	s1 := httprpc.NewServer(nil)
+	if err := s1.Register("/arith", &Arith{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	s1.AddMiddleware(httprpc.NewRecorder(&buf))
+	svr1 := httptest.NewServer(s1)
+	defer svr1.Close()
+
+	c := httprpc.NewClient(svr1.URL, nil)
+	for i := 0; i < 3; i++ {
+		var reply Reply
+		if err := c.Call(context.Background(), "/arith/Add", Args{A: i, B: 1}, &reply); err != nil {
+			t.Fatalf("Call: %v", err)
+		}
+	}
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecords: %v", err)
+	}
+	if got, want := len(records), 3; got != want {
+		t.Fatalf("records: got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		offset int
+		rules  []string
+		ok     bool
+	}{
+		{offset: 0, rules: []string{"Time"}, ok: true},
+		{offset: 0, rules: nil, ok: false},
+		{offset: 1, rules: []string{"/arith/*:Time"}, ok: false},
+	}
+	for i, tt := range tests {
+		s2 := httprpc.NewServer(nil)
+		if err := s2.Register("/arith", &Arith{offset: tt.offset, now: 100}); err != nil {
+			t.Fatalf("case%d: Register: %v", i, err)
+		}
+		svr2 := httptest.NewServer(s2)
+		p := Replayer{URL: svr2.URL, Rules: mustParseRules(t, tt.rules...)}
+		for _, res := range p.ReplayAll(context.Background(), records) {
+			if got, want := res.OK(), tt.ok; got != want {
+				t.Errorf("case%d: %s: ok: got %v, want %v, err=%v, diffs=%v", i, res.Record.Path, got, want, res.Err, res.Diffs)
+			}
+		}
+		svr2.Close()
+	}
+}
@@ -0,0 +1,192 @@
+package httprpc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// route 带路径参数的类, 如/devices/{id}, 其方法路径为/devices/{id}/Reboot.
+type route struct {
+	name     string
+	segments []string
+	params   []string
+}
+
+func isTemplate(path string) bool {
+	return strings.ContainsAny(path, "{}")
+}
+
+func isParam(seg string) bool {
+	return len(seg) > 2 && seg[0] == '{' && seg[len(seg)-1] == '}'
+}
+
+// parseRoute 解析类名模板, 参数必须占据整个路径段且不能重名.
+func parseRoute(name string) (*route, error) {
+	r := &route{name: name, segments: strings.Split(name[1:], "/")}
+	seen := make(map[string]bool)
+	for _, seg := range r.segments {
+		if !isParam(seg) {
+			if isTemplate(seg) {
+				return nil, fmt.Errorf("invalid path template %s: segment %s", name, seg)
+			}
+			continue
+		}
+		param := seg[1 : len(seg)-1]
+		if isTemplate(param) || seen[param] {
+			return nil, fmt.Errorf("invalid path template %s: param %s", name, seg)
+		}
+		seen[param] = true
+		r.params = append(r.params, param)
+	}
+	return r, nil
+}
+
+// pathParams 返回路径中的参数名
+func pathParams(path string) []string {
+	var params []string
+	for _, seg := range strings.Split(path, "/") {
+		if isParam(seg) {
+			params = append(params, seg[1:len(seg)-1])
+		}
+	}
+	return params
+}
+
+// shape 将参数名替换为空后的模板, shape相同的两个模板匹配相同的路径.
+func (r *route) shape() string {
+	segs := make([]string, len(r.segments))
+	for i, seg := range r.segments {
+		if isParam(seg) {
+			seg = "{}"
+		}
+		segs[i] = seg
+	}
+	return strings.Join(segs, "/")
+}
+
+func (r *route) match(segs []string) (map[string]string, bool) {
+	if len(segs) != len(r.segments) {
+		return nil, false
+	}
+	var params map[string]string
+	for i, seg := range r.segments {
+		if !isParam(seg) {
+			if seg != segs[i] {
+				return nil, false
+			}
+			continue
+		}
+		if segs[i] == "" {
+			return nil, false
+		}
+		if params == nil {
+			params = make(map[string]string, len(r.params))
+		}
+		params[seg[1:len(seg)-1]] = segs[i]
+	}
+	return params, true
+}
+
+// moreSpecific 从左到右比较, 先出现常量段的模板更具体.
+func (r *route) moreSpecific(o *route) bool {
+	for i := 0; i < len(r.segments) && i < len(o.segments); i++ {
+		if p, q := isParam(r.segments[i]), isParam(o.segments[i]); p != q {
+			return q
+		}
+	}
+	return len(r.segments) > len(o.segments)
+}
+
+// addRoute 检查冲突并按具体程度插入路由表, 调用者需持有registerMu.
+func (s *Server) addRoute(r *route) error {
+	routes, _ := s.routes.Load().([]*route)
+	for _, o := range routes {
+		if o.name == r.name {
+			return nil
+		}
+		if o.shape() == r.shape() {
+			return fmt.Errorf("route conflict: %s and %s", r.name, o.name)
+		}
+	}
+	routes = append(append([]*route(nil), routes...), r)
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].moreSpecific(routes[j]) })
+	s.routes.Store(routes)
+	return nil
+}
+
+// matchRoute 返回匹配className且包含方法methodName的最具体的路由
+func (s *Server) matchRoute(className, methodName string) (*method, map[string]string) {
+	routes, _ := s.routes.Load().([]*route)
+	if len(routes) <= 0 {
+		return nil, nil
+	}
+	segs := strings.Split(className[1:], "/")
+	for _, r := range routes {
+		params, ok := r.match(segs)
+		if !ok {
+			continue
+		}
+		v, ok := s.classes.Load(r.name)
+		if !ok {
+			continue
+		}
+		if meth, ok := v.(*class).methods[methodName]; ok {
+			return meth, params
+		}
+	}
+	return nil, nil
+}
+
+// lookupRoute 先按注册路径查找方法, 找不到时按路径模板匹配.
+func (s *Server) lookupRoute(path string) (*method, map[string]string, error) {
+	className, methodName := splitPath(s.resolvePath(normalizePath(path)))
+	_, meth, err := s.lookupMethod(className, methodName)
+	if err == nil && !isTemplate(className) {
+		return meth, nil, nil
+	}
+	if meth, params := s.matchRoute(className, methodName); meth != nil {
+		return meth, params, nil
+	}
+	return nil, nil, fmt.Errorf("lookup method: %v", err)
+}
+
+// setPathFields 记录args中带path tag的字段, tag必须是路由中的参数且字段为基本类型.
+func setPathFields(r *route, m *method) error {
+	t := m.args
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		param := f.Tag.Get("path")
+		if param == "" {
+			continue
+		}
+		if !containsString(r.params, param) {
+			return fmt.Errorf("%s: args field %s: route %s has no path param %s", m.path, f.Name, r.name, param)
+		}
+		if !isScalarType(f.Type) {
+			return fmt.Errorf("%s: args field %s: unsupported path param type %s", m.path, f.Name, f.Type)
+		}
+		if m.pathFields == nil {
+			m.pathFields = make(map[string][]int)
+		}
+		m.pathFields[param] = f.Index
+	}
+	return nil
+}
+
+// bindPath 将路径参数绑定到args中带path tag的字段
+func bindPath(params map[string]string, fields map[string][]int, argsPtr reflect.Value) error {
+	for param, index := range fields {
+		if err := setScalar(argsPtr.Elem().FieldByIndex(index), param, params[param]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
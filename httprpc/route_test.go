@@ -0,0 +1,188 @@
+package httprpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type DeviceArgs struct {
+	ID    int64  `path:"id"`
+	Force bool   `json:",omitempty"`
+	Where string `json:",omitempty"`
+}
+
+type Device struct{}
+
+func (Device) Reboot(ctx context.Context, args DeviceArgs, reply *DeviceArgs) error {
+	*reply = args
+	reply.Where = "template"
+	return nil
+}
+
+func (Device) Name(ctx context.Context, args interface{}, reply *string) error {
+	*reply = ctx.(*Context).PathParam("id")
+	return nil
+}
+
+type DeviceGroup struct{}
+
+func (DeviceGroup) Reboot(ctx context.Context, args DeviceArgs, reply *DeviceArgs) error {
+	reply.Where = "literal"
+	return nil
+}
+
+func TestServeHTTPPathParams(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/devices/{id}", Device{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/devices/all", DeviceGroup{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.RegisterFunc("/devices/{id}/ports/{port}/Open", func(ctx context.Context, args struct {
+		ID   int64  `path:"id"`
+		Port string `path:"port"`
+	}) (string, error) {
+		return args.Port, nil
+	}); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+	if err := s.RegisterFunc("/devices/{id}/ports/main/Open", func(ctx context.Context) (string, error) {
+		return "main", nil
+	}); err != nil {
+		t.Fatalf("RegisterFunc: %v", err)
+	}
+	var a Arith
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var reply DeviceArgs
+	if err := callTestServer(s, "/devices/42/Reboot", DeviceArgs{ID: 1, Force: true}, &reply); err != nil {
+		t.Fatalf("callTestServer: %v", err)
+	}
+	if want := (DeviceArgs{ID: 42, Force: true, Where: "template"}); reply != want {
+		t.Fatalf("reply: got %+v, want %+v", reply, want)
+	}
+
+	reply = DeviceArgs{}
+	if err := callTestServer(s, "/devices/all/Reboot", DeviceArgs{}, &reply); err != nil {
+		t.Fatalf("callTestServer: %v", err)
+	}
+	if got, want := reply.Where, "literal"; got != want {
+		t.Fatalf("literal class: got %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		path  string
+		reply string
+	}{
+		{path: "/devices/all/Name", reply: "all"},
+		{path: "/devices/7/Name", reply: "7"},
+		{path: "/devices/7/ports/usb/Open", reply: "usb"},
+		{path: "/devices/7/ports/main/Open", reply: "main"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := callTestServer(s, tt.path, nil, &got); err != nil {
+			t.Fatalf("callTestServer(%s): %v", tt.path, err)
+		}
+		if got != tt.reply {
+			t.Fatalf("callTestServer(%s): got %s, want %s", tt.path, got, tt.reply)
+		}
+	}
+
+	for _, path := range []string{"/devices/abc/Reboot", "/devices/7/Unknown", "/devices/7/ports/Open"} {
+		err := callTestServer(s, path, DeviceArgs{}, &reply)
+		if got, want := GetErrorCode(err), codes.InvalidPath; got != want {
+			t.Fatalf("callTestServer(%s): code: got %v, want %v", path, got, want)
+		}
+	}
+
+	var c Reply
+	if err := callTestServer(s, "/arith/Add", Args{A: 1, B: 2}, &c); err != nil || c.C != 3 {
+		t.Fatalf("callTestServer(/arith/Add): %v, %v", c, err)
+	}
+}
+
+func TestRegisterRouteError(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/devices/{id}", Device{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		fn   func() error
+		err  string
+	}{
+		{
+			name: "conflict",
+			fn:   func() error { return s.Register("/devices/{name}", new(Arith)) },
+			err:  "route conflict",
+		},
+		{
+			name: "conflict func",
+			fn: func() error {
+				return s.RegisterFunc("/devices/{name}/Ping", func(context.Context) (int, error) { return 0, nil })
+			},
+			err: "route conflict",
+		},
+		{
+			name: "duplicate",
+			fn:   func() error { return s.Register("/devices/{id}", Device{}) },
+			err:  "class already defined",
+		},
+		{
+			name: "partial segment",
+			fn:   func() error { return s.Register("/users/u{id}", Device{}) },
+			err:  "invalid path template",
+		},
+		{
+			name: "duplicate param",
+			fn:   func() error { return s.Register("/users/{id}/groups/{id}", Device{}) },
+			err:  "invalid path template",
+		},
+		{
+			name: "missing param",
+			fn:   func() error { return s.Register("/users/{uid}", Device{}) },
+			err:  "has no path param id",
+		},
+		{
+			name: "method param",
+			fn: func() error {
+				return s.RegisterFunc("/users/{id}", func(context.Context) (int, error) { return 0, nil })
+			},
+			err: "method name can not be a path template",
+		},
+	}
+	for _, tt := range tests {
+		err := tt.fn()
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Fatalf("%s: got %v, want %q", tt.name, err, tt.err)
+		}
+		t.Logf("%s: %v", tt.name, err)
+	}
+}
+
+func TestRouteMoreSpecific(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		expect bool
+	}{
+		{a: "/a/b/{x}", b: "/a/{x}/c", expect: true},
+		{a: "/a/{x}/c", b: "/a/b/{x}", expect: false},
+		{a: "/a/{x}/c", b: "/a/{x}/{y}", expect: true},
+		{a: "/{x}/b", b: "/a/{y}", expect: false},
+	}
+	for _, tt := range tests {
+		a, _ := parseRoute(tt.a)
+		b, _ := parseRoute(tt.b)
+		if got := a.moreSpecific(b); got != tt.expect {
+			t.Errorf("%s more specific than %s: %v != %v", tt.a, tt.b, got, tt.expect)
+		}
+	}
+}
@@ -1,21 +1,43 @@
 package httprpc
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"hash/fnv"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
 )
 
+// ReadOnly 接收者实现该接口时, ReadOnlyMethods返回的方法被标记为只读, 可以使用GET调用.
+type ReadOnly interface {
+	ReadOnlyMethods() []string
+}
+
 type Server struct {
-	codec   Codec
-	classes sync.Map
-	next    NextMiddleware
+	codec           Codec
+	classes         sync.Map
+	aliases         sync.Map
+	deprecations    sync.Map
+	cors            *CORS
+	pooling         bool
+	bodyLimit       int64
+	panicOptions    PanicOptions
+	flights         flightGroup
+	coalesceHeaders []string
+	errorMapper     *ErrorMapper
+	stackPolicy     StackPolicy
+	registerMu      sync.Mutex
+	routes          atomic.Value // []*route
+	next            NextMiddleware
 }
 
 func NewServer(codec Codec) *Server {
@@ -37,17 +59,142 @@ func (s *Server) register(rcvr interface{}, name string) error {
 	}
 
 	name = normalizePath(name)
+	if _, ok := s.aliases.Load(name); ok {
+		return fmt.Errorf("register: class already defined as alias: %s", name)
+	}
 	c, err := parseClass(name, val)
 	if err != nil {
 		return fmt.Errorf("register: parse class: %v", err)
 	}
 
-	if _, loaded := s.classes.LoadOrStore(name, c); loaded {
+	if ro, ok := rcvr.(ReadOnly); ok {
+		for _, mname := range ro.ReadOnlyMethods() {
+			m, ok := c.methods[mname]
+			if !ok {
+				return fmt.Errorf("register: read only method not found: %s", joinPath(name, mname))
+			}
+			m.readOnly = true
+		}
+	}
+
+	var rt *route
+	if isTemplate(name) {
+		if rt, err = parseRoute(name); err != nil {
+			return fmt.Errorf("register: %v", err)
+		}
+		for _, m := range c.methods {
+			if err = setPathFields(rt, m); err != nil {
+				return fmt.Errorf("register: %v", err)
+			}
+		}
+	}
+
+	s.registerMu.Lock()
+	defer s.registerMu.Unlock()
+	if _, ok := s.classes.Load(name); ok {
 		return fmt.Errorf("register: class already defined: %s", name)
 	}
+	if rt != nil {
+		if err = s.addRoute(rt); err != nil {
+			return fmt.Errorf("register: %v", err)
+		}
+	}
+	s.classes.Store(name, c)
+	return nil
+}
+
+// RegisterFunc 将函数fn注册到path, fn的签名与方法相同但没有接收者, 例如
+// func(ctx context.Context, args *Args) (*Reply, error).
+// 同一前缀下可以注册多个函数, 但不能与Register注册的类重名.
+func (s *Server) RegisterFunc(path string, fn interface{}) error {
+	path = normalizePath(path)
+	name, mname := splitPath(path)
+	if mname == "" {
+		return fmt.Errorf("register func: invalid path: %s", path)
+	}
+	if _, ok := s.aliases.Load(name); ok {
+		return fmt.Errorf("register func: class already defined as alias: %s", name)
+	}
+	if isTemplate(mname) {
+		return fmt.Errorf("register func: method name can not be a path template: %s", path)
+	}
+	m, err := parseFunc(path, fn)
+	if err != nil {
+		return fmt.Errorf("register func: %v", err)
+	}
+	var rt *route
+	if isTemplate(name) {
+		if rt, err = parseRoute(name); err != nil {
+			return fmt.Errorf("register func: %v", err)
+		}
+		if err = setPathFields(rt, m); err != nil {
+			return fmt.Errorf("register func: %v", err)
+		}
+	}
+
+	// 服务过程中类的methods只读, 注册时复制后整体替换
+	s.registerMu.Lock()
+	defer s.registerMu.Unlock()
+	methods := map[string]*method{mname: m}
+	if v, ok := s.classes.Load(name); ok {
+		c := v.(*class)
+		if !c.funcs {
+			return fmt.Errorf("register func: class already defined: %s", name)
+		}
+		if _, ok = c.methods[mname]; ok {
+			return fmt.Errorf("register func: function already defined: %s", path)
+		}
+		for k, v := range c.methods {
+			methods[k] = v
+		}
+	}
+	if rt != nil {
+		if err = s.addRoute(rt); err != nil {
+			return fmt.Errorf("register func: %v", err)
+		}
+	}
+	s.classes.Store(name, &class{name: name, funcs: true, methods: methods})
+	return nil
+}
+
+// SetCoalesce 对已注册的方法开启请求合并, 需在开始服务前调用.
+// 参数相同的并发调用只执行一次方法, 各调用方得到相同的响应.
+// 上传文件或流式读写的方法不能合并.
+func (s *Server) SetCoalesce(paths ...string) error {
+	for _, path := range paths {
+		_, meth, err := s.lookupByPath(path)
+		if err != nil {
+			return fmt.Errorf("set coalesce: %v", err)
+		}
+		if meth.rawArgs || meth.streamReply {
+			return fmt.Errorf("set coalesce: method %s streams args or reply", path)
+		}
+		if meth.files != nil {
+			return fmt.Errorf("set coalesce: method %s accepts files", path)
+		}
+		meth.coalesce = true
+	}
 	return nil
 }
 
+// SetReadOnly 将已注册的方法标记为只读, 需在开始服务前调用.
+func (s *Server) SetReadOnly(paths ...string) error {
+	for _, path := range paths {
+		_, meth, err := s.lookupByPath(path)
+		if err != nil {
+			return fmt.Errorf("set read only: %v", err)
+		}
+		meth.readOnly = true
+	}
+	return nil
+}
+
+// SetPooling 设置是否复用指针类型的args和reply, 需在开始服务前调用.
+// 开启后方法返回时args和reply会被回收, 方法不能在返回后继续持有它们.
+func (s *Server) SetPooling(pooling bool) {
+	s.pooling = pooling
+}
+
 func (s *Server) AddMiddleware(middlewares ...Middleware) {
 	if s.next == nil {
 		s.next = s.serveHTTP
@@ -62,47 +209,123 @@ func (s *Server) AddMiddleware(middlewares ...Middleware) {
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "OPTIONS" {
-		setCors(w.Header(), r.Header.Get("Origin"))
+	if r.Method == http.MethodOptions {
+		if err := s.preflight(w, r); err != nil {
+			s.setError(r.Context(), w, err, r)
+		}
+		return
+	}
+	if err := s.checkOrigin(w.Header(), r); err != nil {
+		s.setError(r.Context(), w, err, r)
 		return
 	}
 
 	next := s.next
+	var meth *method
 	if next == nil {
 		next = s.serveHTTP
+	} else {
+		// 中间件据此判断方法类型, 读取请求体时同样受大小上限限制
+		meth, _, _ = s.lookupRoute(r.URL.Path)
+		s.limitBody(r, meth)
 	}
+	traceID, span := startServerSpan(r)
 	ctx := &Context{
-		Context:  context.Background(),
-		TraceID:  getHeaderTraceID(r.Header),
-		Request:  r,
-		Response: w,
+		Context:    tracing.ContextWithSpan(context.Background(), span),
+		TraceID:    traceID,
+		Request:    r,
+		Response:   w,
+		MethodPath: s.ResolvePath(r.URL.Path),
+		method:     meth,
 	}
-	if err := next(ctx, w, r); err != nil {
-		s.setError(w, err, r)
+	err := s.mapError(next(ctx, w, r))
+	if err != nil {
+		s.setError(ctx, w, err, r)
 	}
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("rpc.code", int(GetErrorCode(err)))
+	span.Finish()
 }
 
 func (s *Server) serveHTTP(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	// check
-	if err := s.checkContentType(r.Header); err != nil {
+	meth, params, lerr := s.lookupRoute(r.URL.Path)
+	if err := s.checkContentType(r.Header, meth); err != nil {
 		return NewError(codes.InvalidHeader, err)
 	}
 
 	// lookup method
-	rcvr, meth, err := s.lookupByPath(r.URL.Path)
-	if err != nil {
-		return NewError(codes.InvalidPath, err)
+	if lerr != nil {
+		return NewError(codes.InvalidPath, lerr)
 	}
+	s.checkDeprecation(ctx, w, r)
 
 	// decode args
-	args, err := s.decodeArgs(r.Body, meth.args)
+	if r.Method == http.MethodGet && !meth.readOnly {
+		return Errorf(codes.MethodNotAllowed, "method %s is not read only", r.URL.Path)
+	}
+	body, err := s.wrapBody(r, meth)
 	if err != nil {
-		return NewError(codes.DecodeBodyFail, err)
+		return err
+	}
+	pooling := s.pooling
+	v := meth.alloc(pooling)
+	defer meth.free(v, pooling)
+	switch {
+	case r.Method == http.MethodGet:
+		if err = s.bindArgs(r.URL.Query(), v.argsPtr); err != nil {
+			return NewError(codes.DecodeQueryFail, err)
+		}
+	case meth.rawArgs:
+		v.args.Set(reflect.ValueOf(r.Body))
+	case meth.files != nil && isMultipart(r.Header):
+		err = s.decodeMultipart(r, meth.files, v.argsPtr)
+		if r.MultipartForm != nil {
+			defer r.MultipartForm.RemoveAll()
+		}
+		if berr := body.check(); berr != nil {
+			return berr
+		}
+		if err != nil {
+			return NewError(codes.DecodeBodyFail, err)
+		}
+	default:
+		// 路径参数可以代替请求体, 此时允许请求体为空
+		err = s.decodeArgs(r.Body, meth.args, v.argsPtr)
+		if berr := body.check(); berr != nil {
+			return berr
+		}
+		if err != nil && !(err == io.EOF && params != nil) {
+			return NewError(codes.DecodeBodyFail, err)
+		}
+	}
+	if params != nil {
+		if err = bindPath(params, meth.pathFields, v.argsPtr); err != nil {
+			return NewError(codes.InvalidPath, err)
+		}
+		if rctx, ok := ctx.(*Context); ok {
+			rctx.PathParams = params
+		}
 	}
 
 	// call method
-	reply := newReply(meth.reply)
-	if err = call(ctx, meth.method, rcvr, args, reply); err != nil {
+	if err = s.checkDisabled(meth); err != nil {
+		return err
+	}
+	if meth.coalesce {
+		if err = body.check(); err != nil {
+			return err
+		}
+		return s.serveCoalesced(ctx, w, r, meth, params, v)
+	}
+	reply := v.reply
+	if err = s.call(ctx, meth, v.args, reply); err != nil {
+		if berr := body.check(); berr != nil {
+			return berr
+		}
+		return err
+	}
+	if err = body.check(); err != nil {
 		return err
 	}
 
@@ -110,7 +333,14 @@ func (s *Server) serveHTTP(ctx context.Context, w http.ResponseWriter, r *http.R
 	s.setResponseHeader(w, ctx, r)
 
 	// encode reply
+	if meth.streamReply {
+		s.writeStream(ctx, w, r, reply.Interface())
+		return nil
+	}
 	if !isNilInterface(meth.reply) {
+		if r.Method == http.MethodGet {
+			return s.encodeCacheable(w, r, reply.Interface())
+		}
 		if err = s.codec.Encode(w, reply.Interface()); err != nil {
 			return NewError(codes.EncodeBodyFail, err)
 		}
@@ -119,7 +349,12 @@ func (s *Server) serveHTTP(ctx context.Context, w http.ResponseWriter, r *http.R
 	return nil
 }
 
-func (s *Server) checkContentType(h http.Header) error {
+// checkContentType 检查请求的Content-Type, io.Reader参数的方法接受任意类型,
+// 带文件字段的方法还接受multipart/form-data.
+func (s *Server) checkContentType(h http.Header, meth *method) error {
+	if meth != nil && (meth.rawArgs || meth.files != nil && isMultipart(h)) {
+		return nil
+	}
 	if v := getHeaderContentType(h); v != "" && v != s.codec.ContentType() {
 		return fmt.Errorf("not support %s Content-Type header, the Content-Type header must be %s or empty", v, s.codec.ContentType())
 	}
@@ -127,7 +362,7 @@ func (s *Server) checkContentType(h http.Header) error {
 }
 
 func (s *Server) lookupByPath(path string) (rcvr reflect.Value, meth *method, err error) {
-	className, methodName := splitPath(path)
+	className, methodName := splitPath(s.resolvePath(normalizePath(path)))
 	rcvr, meth, err = s.lookupMethod(className, methodName)
 	if err != nil {
 		return reflect.Value{}, nil, fmt.Errorf("lookup method: %v", err)
@@ -148,23 +383,58 @@ func (s *Server) lookupMethod(className, methodName string) (reflect.Value, *met
 	return c.rcvr, meth, nil
 }
 
-func (s *Server) decodeArgs(r io.Reader, argsType reflect.Type) (args reflect.Value, err error) {
-	isValue := false
-	if argsType.Kind() == reflect.Ptr {
-		args = reflect.New(argsType.Elem())
-	} else {
-		args = reflect.New(argsType)
-		isValue = true
+func (s *Server) decodeArgs(r io.Reader, argsType reflect.Type, argsPtr reflect.Value) error {
+	if isNilInterface(argsType) {
+		return nil
+	}
+	return s.codec.Decode(r, argsPtr.Interface())
+}
+
+func (s *Server) bindArgs(q url.Values, argsPtr reflect.Value) error {
+	return bindQuery(q, argsPtr.Elem())
+}
+
+// encodeCacheable 编码GET请求的响应, 设置ETag并处理条件请求.
+func (s *Server) encodeCacheable(w http.ResponseWriter, r *http.Request, reply interface{}) error {
+	var buf bytes.Buffer
+	if err := s.codec.Encode(&buf, reply); err != nil {
+		return NewError(codes.EncodeBodyFail, err)
+	}
+	writeCacheable(w, r, buf.Bytes())
+	return nil
+}
+
+func writeCacheable(w http.ResponseWriter, r *http.Request, body []byte) {
+	h := fnv.New64a()
+	h.Write(body)
+	etag := fmt.Sprintf(`"%x"`, h.Sum64())
+	w.Header().Set("ETag", etag)
+	if notModified(r.Header, w.Header(), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
-	if !isNilInterface(argsType) {
-		if err = s.codec.Decode(r, args.Interface()); err != nil {
-			return args, err
+	w.Write(body)
+}
+
+func notModified(req, resp http.Header, etag string) bool {
+	if inm := req.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
 		}
+		return false
 	}
-	if isValue {
-		args = args.Elem()
+	ims, err := http.ParseTime(req.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(resp.Get("Last-Modified"))
+	if err != nil {
+		return false
 	}
-	return args, nil
+	return !lm.After(ims)
 }
 
 func newReply(replyType reflect.Type) (reply reflect.Value) {
@@ -172,42 +442,35 @@ func newReply(replyType reflect.Type) (reply reflect.Value) {
 		reply = reflect.New(replyType)
 	} else {
 		reply = reflect.New(replyType.Elem())
-		switch replyType.Elem().Kind() {
-		case reflect.Map:
-			reply.Elem().Set(reflect.MakeMap(replyType.Elem()))
-		case reflect.Slice:
-			reply.Elem().Set(reflect.MakeSlice(replyType.Elem(), 0, 0))
-		}
+		initReply(replyType, reply)
 	}
 	return reply
 }
 
-func call(ctx context.Context, method reflect.Method, rcvr, args, reply reflect.Value) (err error) {
+func initReply(replyType reflect.Type, reply reflect.Value) {
+	if isNilInterface(replyType) {
+		return
+	}
+	switch replyType.Elem().Kind() {
+	case reflect.Map:
+		reply.Elem().Set(reflect.MakeMap(replyType.Elem()))
+	case reflect.Slice:
+		reply.Elem().Set(reflect.MakeSlice(replyType.Elem(), 0, 0))
+	}
+}
+
+func (s *Server) call(ctx context.Context, meth *method, args, reply reflect.Value) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			buf := runtimeStack()
-			log.Printf("panic: %v\n%s", r, buf)
-
-			if e, ok := r.(error); ok {
-				err = e
-			} else {
-				err = fmt.Errorf("%v", r)
-			}
-			err = NewError(codes.Panic, err)
+			err = s.recoverPanic(ctx, meth, r)
 		}
 	}()
 
-	rets := method.Func.Call([]reflect.Value{rcvr, reflect.ValueOf(ctx), args, reply})
-	erri := rets[0].Interface()
-	if erri != nil {
-		err = erri.(error)
-	}
-	return err
+	return s.mapError(meth.invoke(ctx, args, reply))
 }
 
 func (s *Server) setResponseHeader(w http.ResponseWriter, ctx context.Context, r *http.Request) {
 	setHeaderContentType(w.Header(), s.codec.ContentType())
-	setCors(w.Header(), r.Header.Get("Origin"))
 	if rctx, ok := ctx.(*Context); ok {
 		setHeaderTraceID(w.Header(), rctx.TraceID)
 		for key, values := range rctx.ResponseHeader {
@@ -218,16 +481,23 @@ func (s *Server) setResponseHeader(w http.ResponseWriter, ctx context.Context, r
 	}
 }
 
-func (s *Server) setError(w http.ResponseWriter, err error, r *http.Request) {
-	code, cause, stack := GetErrorCode(err), GetErrorCause(err), GetErrorStack(err)
-	er := errReply{
-		Code:  int(code),
-		Error: code.String(),
-		Cause: cause.Error(),
-		Stack: string(stack),
-	}
+func (s *Server) setError(ctx context.Context, w http.ResponseWriter, err error, r *http.Request) {
+	code, er := newErrReply(err, s.exposeStack(ctx, r))
 	setHeaderContentType(w.Header(), s.codec.ContentType())
-	setCors(w.Header(), r.Header.Get("Origin"))
 	w.WriteHeader(code.Status())
 	s.codec.Encode(w, er)
 }
+
+func newErrReply(err error, withStack bool) (codes.Code, errReply) {
+	code, cause := GetErrorCode(err), GetErrorCause(err)
+	er := errReply{
+		Code:    int(code),
+		Error:   code.String(),
+		Cause:   cause.Error(),
+		Details: errorDetails(err),
+	}
+	if withStack {
+		er.Stack = string(GetErrorStack(err))
+	}
+	return code, er
+}
@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
 )
@@ -310,3 +312,97 @@ func TestServerMiddleware(t *testing.T) {
 		t.Fatalf("callTestServer: %v", err)
 	}
 }
+
+func TestServerMethods(t *testing.T) {
+	var a Arith
+	var p Printer
+	s := NewServer(nil)
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/", p); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	var paths []string
+	for _, m := range s.Methods() {
+		paths = append(paths, m.Path)
+	}
+	want := []string{"/Println", "/arith/Add", "/arith/Div", "/arith/Error", "/arith/Mul", "/arith/Scan", "/arith/String"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("methods: got %v, want %v", paths, want)
+	}
+}
+
+type Catalog struct {
+	modified time.Time
+}
+
+func (c *Catalog) ReadOnlyMethods() []string {
+	return []string{"Get"}
+}
+
+func (c *Catalog) Get(ctx *Context, args *Args, reply *Reply) error {
+	ctx.SetLastModified(c.modified)
+	reply.C = args.A + args.B
+	return nil
+}
+
+func (c *Catalog) Set(ctx context.Context, args *Args, reply *Reply) error {
+	return nil
+}
+
+func TestServeHTTPGet(t *testing.T) {
+	var a Arith
+	c := Catalog{modified: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewServer(nil)
+	if err := s.Register("/catalog", &c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetReadOnly("/arith/Add"); err != nil {
+		t.Fatalf("SetReadOnly: %v", err)
+	}
+	if err := s.SetReadOnly("/arith/NotFound"); err == nil {
+		t.Fatalf("SetReadOnly return error is nil")
+	}
+
+	tests := []struct {
+		path   string
+		header http.Header
+		status int
+		body   string
+	}{
+		{path: "/arith/Add?A=1&B=2", status: http.StatusOK, body: `{"C":3}`},
+		{path: "/arith/Mul?A=1&B=2", status: http.StatusMethodNotAllowed},
+		{path: "/arith/Add?A=x", status: http.StatusBadRequest},
+		{path: "/catalog/Get?A=2&B=2", status: http.StatusOK, body: `{"C":4}`},
+		{path: "/catalog/Set?A=2&B=2", status: http.StatusMethodNotAllowed},
+		{path: "/catalog/Get?A=2&B=2", header: http.Header{"If-None-Match": {`W/"x", "260a9f174292010c"`}}, status: http.StatusNotModified},
+		{path: "/catalog/Get?A=2&B=2", header: http.Header{"If-Modified-Since": {"Wed, 01 Jan 2020 00:00:00 GMT"}}, status: http.StatusNotModified},
+		{path: "/catalog/Get?A=2&B=2", header: http.Header{"If-Modified-Since": {"Tue, 31 Dec 2019 00:00:00 GMT"}}, status: http.StatusOK},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		for k, v := range tt.header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if got, want := w.Code, tt.status; got != want {
+			t.Errorf("case%d: %s: status: got %v, want %v, body: %s", i, tt.path, got, want, w.Body)
+			continue
+		}
+		if tt.body != "" {
+			if got, want := strings.TrimSpace(w.Body.String()), tt.body; got != want {
+				t.Errorf("case%d: %s: body: got %v, want %v", i, tt.path, got, want)
+			}
+			if w.Header().Get("ETag") == "" {
+				t.Errorf("case%d: %s: ETag header not set", i, tt.path)
+			}
+		}
+		t.Logf("case%d: %s: ETag: %s, Last-Modified: %s", i, tt.path, w.Header().Get("ETag"), w.Header().Get("Last-Modified"))
+	}
+}
@@ -0,0 +1,104 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+const maxStackDepth = 64
+
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	return pcs[:runtime.Callers(skip, pcs)]
+}
+
+// StackFilter 调用栈的裁剪和过滤选项.
+//
+// Skip中为函数名前缀, 匹配的帧不输出; MaxFrames大于0时最多输出MaxFrames帧.
+type StackFilter struct {
+	MaxFrames int
+	Skip      []string
+}
+
+// DefaultStackFilter 默认过滤runtime、reflect、net/http和testing中的帧
+var DefaultStackFilter = StackFilter{
+	MaxFrames: 32,
+	Skip:      []string{"runtime.", "reflect.", "net/http.", "testing."},
+}
+
+func (f StackFilter) skip(function string) bool {
+	for _, prefix := range f.Skip {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f StackFilter) format(pcs []uintptr) []byte {
+	var buf bytes.Buffer
+	frames := runtime.CallersFrames(pcs)
+	n := 0
+	for {
+		frame, more := frames.Next()
+		if !f.skip(frame.Function) {
+			if f.MaxFrames > 0 && n >= f.MaxFrames {
+				buf.WriteString("...\n")
+				break
+			}
+			fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+			n++
+		}
+		if !more {
+			break
+		}
+	}
+	return buf.Bytes()
+}
+
+// StackPolicy 决定错误栈是否返回给调用方. 错误栈总是会被记录到日志中.
+type StackPolicy func(ctx context.Context, r *http.Request) bool
+
+// SetStackPolicy 设置错误栈的返回策略, 为nil时不返回错误栈.
+func (s *Server) SetStackPolicy(p StackPolicy) {
+	s.stackPolicy = p
+}
+
+func (s *Server) exposeStack(ctx context.Context, r *http.Request) bool {
+	if s.stackPolicy == nil {
+		return StackTrace
+	}
+	return s.stackPolicy(ctx, r)
+}
+
+// DevelopmentStack 总是返回错误栈, 只应在开发环境中使用.
+func DevelopmentStack(ctx context.Context, r *http.Request) bool {
+	return true
+}
+
+// TrustedClients 向X-Client-Id在ids中的调用方返回错误栈, 只应在client id已由网关验证时使用.
+func TrustedClients(ids ...string) StackPolicy {
+	return func(ctx context.Context, r *http.Request) bool {
+		return containsString(ids, r.Header.Get(xClientID))
+	}
+}
+
+// DebugHeader 请求携带header且X-Client-Id在ids中时返回错误栈.
+func DebugHeader(header string, ids ...string) StackPolicy {
+	return func(ctx context.Context, r *http.Request) bool {
+		return r.Header.Get(header) != "" && containsString(ids, r.Header.Get(xClientID))
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s && v != "" {
+			return true
+		}
+	}
+	return false
+}
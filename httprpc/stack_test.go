@@ -0,0 +1,76 @@
+package httprpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+func TestStackFilter(t *testing.T) {
+	pcs := callers(1)
+	stack := string(DefaultStackFilter.format(pcs))
+	if !strings.Contains(stack, "httprpc.TestStackFilter") {
+		t.Errorf("stack does not contain caller: %s", stack)
+	}
+	if strings.Contains(stack, "testing.tRunner") || strings.Contains(stack, "runtime.") {
+		t.Errorf("stack not filtered: %s", stack)
+	}
+
+	f := StackFilter{MaxFrames: 1}
+	stack = string(f.format(pcs))
+	if got := strings.Count(stack, "\n\t"); got != 1 || !strings.HasSuffix(stack, "...\n") {
+		t.Errorf("stack not trimmed: %s", stack)
+	}
+}
+
+func TestStackPolicy(t *testing.T) {
+	var a Arith
+	s := NewServer(nil)
+	if err := s.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	call := func(header http.Header) errReply {
+		r := httptest.NewRequest("POST", "/arith/Error", strings.NewReader(`{}`))
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		var er errReply
+		if err := s.codec.Decode(w.Body, &er); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return er
+	}
+
+	tests := []struct {
+		policy StackPolicy
+		header http.Header
+		stack  bool
+	}{
+		{policy: nil, stack: false},
+		{policy: DevelopmentStack, stack: true},
+		{policy: TrustedClients("ops"), header: http.Header{"X-Client-Id": {"ops"}}, stack: true},
+		{policy: TrustedClients("ops"), header: http.Header{"X-Client-Id": {"app"}}, stack: false},
+		{policy: DebugHeader("X-Debug", "ops"), header: http.Header{"X-Client-Id": {"ops"}}, stack: false},
+		{policy: DebugHeader("X-Debug", "ops"), header: http.Header{"X-Client-Id": {"ops"}, "X-Debug": {"1"}}, stack: true},
+		{policy: DebugHeader("X-Debug", "ops"), header: http.Header{"X-Debug": {"1"}}, stack: false},
+	}
+	for i, tt := range tests {
+		s.SetStackPolicy(tt.policy)
+		er := call(tt.header)
+		if got := er.Stack != ""; got != tt.stack {
+			t.Errorf("case%d: stack: got %v, want %v", i, got, tt.stack)
+		}
+	}
+
+	// 未设置策略时错误栈仍然被捕获
+	err := Errorf(codes.Unknown, "x")
+	if len(GetErrorStack(err)) <= 0 {
+		t.Errorf("stack not captured")
+	}
+}
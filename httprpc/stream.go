@@ -0,0 +1,297 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/internal/jsonfield"
+)
+
+// xChecksum 请求体或响应体的SHA-256, 十六进制编码
+const xChecksum = "X-Checksum-Sha256"
+
+// multipart表单保存在内存中的上限, 超过的文件写入临时文件
+const multipartMemory = 8 << 20
+
+var (
+	ErrBodyTooLarge = errors.New("http body too large")
+	ErrChecksumFail = errors.New("http body checksum mismatch")
+)
+
+var (
+	typeOfReader      = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	typeOfReadCloser  = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+	typeOfStream      = reflect.TypeOf(Stream{})
+	typeOfFileHeader  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typeOfFileHeaders = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+func init() {
+	DefaultErrorMapper.Is(ErrBodyTooLarge, codes.BodyTooLarge)
+	DefaultErrorMapper.Is(ErrChecksumFail, codes.ChecksumFail)
+}
+
+// Stream 流式响应, reply为Stream、*Stream、io.Reader或io.ReadCloser时响应体直接从Reader复制,
+// 不经过Codec. Reader实现io.Closer时在响应结束后关闭.
+//
+// ContentType为空时为application/octet-stream; ContentLength不大于0时从Reader的Len或Stat方法获取,
+// 都没有时使用chunked编码; Filename不为空时设置Content-Disposition; Checksum为响应体的SHA-256.
+type Stream struct {
+	Reader        io.Reader
+	ContentType   string
+	ContentLength int64
+	Filename      string
+	Checksum      string
+}
+
+func (st *Stream) size() int64 {
+	if st.ContentLength > 0 {
+		return st.ContentLength
+	}
+	switch r := st.Reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case interface{ Stat() (os.FileInfo, error) }:
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size()
+		}
+	}
+	return -1
+}
+
+// isRawArgs args为io.Reader或io.ReadCloser时, 请求体不经过Codec直接传给方法
+func isRawArgs(t reflect.Type) bool {
+	return t == typeOfReader || t == typeOfReadCloser
+}
+
+func isStreamReply(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		return false
+	}
+	switch t.Elem() {
+	case typeOfReader, typeOfReadCloser, typeOfStream, reflect.PtrTo(typeOfStream):
+		return true
+	}
+	return false
+}
+
+// fileFields 返回args中类型为*multipart.FileHeader或[]*multipart.FileHeader的字段
+func fileFields(t reflect.Type) []jsonfield.Field {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var files []jsonfield.Field
+	for _, f := range jsonfield.Fields(t) {
+		if f.Type == typeOfFileHeader || f.Type == typeOfFileHeaders {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// SetBodyLimit 设置请求体大小上限, 超过时返回codes.BodyTooLarge, 需在开始服务前调用.
+// 没有paths时设置所有方法的默认上限, 否则设置指定方法的上限; limit为0时不限制.
+func (s *Server) SetBodyLimit(limit int64, paths ...string) error {
+	if len(paths) <= 0 {
+		s.bodyLimit = limit
+		return nil
+	}
+	for _, path := range paths {
+		_, meth, err := s.lookupByPath(path)
+		if err != nil {
+			return fmt.Errorf("set body limit: %v", err)
+		}
+		meth.bodyLimit = limit
+	}
+	return nil
+}
+
+// limitBody 在中间件之前按方法的上限包装请求体, 中间件(如Cache)读取请求体时同样受限.
+// 大小上限和校验和在serveHTTP中再次检查.
+func (s *Server) limitBody(r *http.Request, meth *method) {
+	limit := s.bodyLimit
+	if meth != nil && meth.bodyLimit != 0 {
+		limit = meth.bodyLimit
+	}
+	if limit > 0 && r.Body != nil {
+		r.Body = &bodyReader{ReadCloser: r.Body, limit: limit}
+	}
+}
+
+// bodyError 中间件读取请求体失败时返回的错误
+func bodyError(err error) error {
+	if err == ErrBodyTooLarge {
+		return NewError(codes.BodyTooLarge, err)
+	}
+	return NewError(codes.DecodeBodyFail, err)
+}
+
+// bodyReader 限制请求体大小, 并在读到结尾时校验X-Checksum-Sha256.
+type bodyReader struct {
+	io.ReadCloser
+	limit int64
+	n     int64
+	hash  hash.Hash
+	sum   []byte
+	err   error
+}
+
+func (s *Server) wrapBody(r *http.Request, meth *method) (*bodyReader, error) {
+	limit := meth.bodyLimit
+	if limit == 0 {
+		limit = s.bodyLimit
+	}
+	checksum := r.Header.Get(xChecksum)
+	if limit <= 0 && checksum == "" {
+		return nil, nil
+	}
+	if limit > 0 && r.ContentLength > limit {
+		return nil, NewError(codes.BodyTooLarge, ErrBodyTooLarge)
+	}
+	b := &bodyReader{ReadCloser: r.Body, limit: limit}
+	if checksum != "" {
+		sum, err := hex.DecodeString(checksum)
+		if err != nil || len(sum) != sha256.Size {
+			return nil, Errorf(codes.InvalidHeader, "invalid %s header: %s", xChecksum, checksum)
+		}
+		b.hash, b.sum = sha256.New(), sum
+	}
+	r.Body = b
+	return b, nil
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if b.limit > 0 && b.n > b.limit {
+		b.err = ErrBodyTooLarge
+		return 0, b.err
+	}
+	if b.hash != nil {
+		b.hash.Write(p[:n])
+		if err == io.EOF && !bytes.Equal(b.hash.Sum(nil), b.sum) {
+			b.err = ErrChecksumFail
+			return n, b.err
+		}
+	}
+	return n, err
+}
+
+// check 读完请求体的剩余部分并校验大小和X-Checksum-Sha256.
+// 解码器读到完整的值后就停止读取, 不读完无法校验.
+func (b *bodyReader) check() error {
+	if b == nil {
+		return nil
+	}
+	if b.err == nil && b.hash != nil {
+		if _, err := io.Copy(ioutil.Discard, b); err != nil && b.err == nil {
+			return NewError(codes.DecodeBodyFail, err)
+		}
+	}
+	if b.err == nil {
+		return nil
+	}
+	if b.err == ErrBodyTooLarge {
+		return NewError(codes.BodyTooLarge, b.err)
+	}
+	return NewError(codes.ChecksumFail, b.err)
+}
+
+func isMultipart(h http.Header) bool {
+	mt, _, _ := mime.ParseMediaType(getHeaderContentType(h))
+	return mt == "multipart/form-data"
+}
+
+// decodeMultipart 将multipart表单绑定到args, 普通字段同查询参数, 文件字段按字段名匹配.
+// 方法返回后表单的临时文件被删除.
+func (s *Server) decodeMultipart(r *http.Request, files []jsonfield.Field, argsPtr reflect.Value) error {
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		return err
+	}
+	// 读完请求体的剩余部分以完成校验
+	if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+		return err
+	}
+	form := r.MultipartForm
+	if err := bindQuery(url.Values(form.Value), argsPtr.Elem()); err != nil {
+		return err
+	}
+	for _, f := range files {
+		fhs := form.File[f.Name]
+		if len(fhs) <= 0 {
+			continue
+		}
+		if f.Type == typeOfFileHeader {
+			f.Value(argsPtr.Elem()).Set(reflect.ValueOf(fhs[0]))
+		} else {
+			f.Value(argsPtr.Elem()).Set(reflect.ValueOf(fhs))
+		}
+	}
+	return nil
+}
+
+// writeStream 将流式reply复制到响应体, 响应头发出后的错误只记录日志.
+func (s *Server) writeStream(ctx context.Context, w http.ResponseWriter, r *http.Request, reply interface{}) {
+	var st Stream
+	switch v := reply.(type) {
+	case *Stream:
+		st = *v
+	case **Stream:
+		if *v != nil {
+			st = **v
+		}
+	case *io.Reader:
+		st.Reader = *v
+	case *io.ReadCloser:
+		if *v != nil {
+			st.Reader = *v
+		}
+	}
+	if c, ok := st.Reader.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	h := w.Header()
+	if st.ContentType == "" {
+		st.ContentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", st.ContentType)
+	if st.Reader == nil {
+		h.Set("Content-Length", "0")
+		return
+	}
+	if size := st.size(); size >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	if st.Filename != "" {
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": st.Filename}))
+	}
+	if st.Checksum != "" {
+		h.Set(xChecksum, strings.ToLower(st.Checksum))
+	}
+	if _, err := io.Copy(w, st.Reader); err != nil {
+		Logger().WithContext(ctx).Warnw("write stream", "path", r.URL.Path, "error", err)
+	}
+}
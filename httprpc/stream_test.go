@@ -0,0 +1,223 @@
+package httprpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/httprpc/codes"
+)
+
+type UploadReply struct {
+	Size int64
+	Sum  string
+	Name string `json:",omitempty"`
+}
+
+type ImageArgs struct {
+	Version string
+	Image   *multipart.FileHeader
+	Notes   []*multipart.FileHeader
+}
+
+type Firmware struct{}
+
+func (Firmware) Upload(ctx context.Context, r io.Reader) (UploadReply, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return UploadReply{}, err
+	}
+	return UploadReply{Size: n, Sum: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func (Firmware) Image(ctx context.Context, args *ImageArgs) (UploadReply, error) {
+	f, err := args.Image.Open()
+	if err != nil {
+		return UploadReply{}, err
+	}
+	defer f.Close()
+	reply, err := Firmware{}.Upload(ctx, f)
+	reply.Name = args.Version + "/" + args.Image.Filename
+	return reply, err
+}
+
+func (Firmware) Download(ctx context.Context, name string) (*Stream, error) {
+	return &Stream{Reader: strings.NewReader("image:" + name), ContentType: "application/x-binary", Filename: name + ".bin"}, nil
+}
+
+func (Firmware) Logs(ctx context.Context, args interface{}, reply *io.Reader) error {
+	*reply = io.MultiReader(strings.NewReader("line1\n"), strings.NewReader("line2\n"))
+	return nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func serveStream(s *Server, path, contentType string, body []byte, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", path, bytes.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func decodeUploadReply(t *testing.T, w *httptest.ResponseRecorder) UploadReply {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d, body: %s", w.Code, w.Body)
+	}
+	var reply UploadReply
+	if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+		t.Fatalf("decode reply: %v", err)
+	}
+	return reply
+}
+
+func decodeErrCode(t *testing.T, w *httptest.ResponseRecorder) codes.Code {
+	t.Helper()
+	var er errReply
+	if err := json.Unmarshal(w.Body.Bytes(), &er); err != nil {
+		t.Fatalf("decode errReply: %v, body: %s", err, w.Body)
+	}
+	return codes.Code(er.Code)
+}
+
+func TestStreamUpload(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/firmware", Firmware{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetBodyLimit(16, "/firmware/Upload"); err != nil {
+		t.Fatalf("SetBodyLimit: %v", err)
+	}
+	image := []byte("firmware-v1")
+
+	w := serveStream(s, "/firmware/Upload", "application/octet-stream", image, http.Header{xChecksum: {checksum(image)}})
+	if got, want := decodeUploadReply(t, w), (UploadReply{Size: int64(len(image)), Sum: checksum(image)}); got != want {
+		t.Fatalf("reply: got %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		name   string
+		body   []byte
+		header http.Header
+		code   codes.Code
+	}{
+		{name: "checksum", body: image, header: http.Header{xChecksum: {checksum([]byte("other"))}}, code: codes.ChecksumFail},
+		{name: "bad checksum header", body: image, header: http.Header{xChecksum: {"xyz"}}, code: codes.InvalidHeader},
+		{name: "too large", body: bytes.Repeat([]byte("x"), 17), code: codes.BodyTooLarge},
+	}
+	for _, tt := range tests {
+		w := serveStream(s, "/firmware/Upload", "application/octet-stream", tt.body, tt.header)
+		if got, want := decodeErrCode(t, w), tt.code; got != want {
+			t.Fatalf("%s: code: got %v, want %v", tt.name, got, want)
+		}
+		if got, want := w.Code, tt.code.Status(); got != want {
+			t.Fatalf("%s: status: got %d, want %d", tt.name, got, want)
+		}
+	}
+
+	// Content-Length未知时在读取过程中限制大小
+	r := httptest.NewRequest("POST", "/firmware/Upload", io.MultiReader(bytes.NewReader(bytes.Repeat([]byte("x"), 32))))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if got, want := decodeErrCode(t, w), codes.BodyTooLarge; got != want {
+		t.Fatalf("chunked: code: got %v, want %v", got, want)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/firmware", Firmware{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	image := []byte("firmware-v2")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("Version", "2.0")
+	fw, _ := mw.CreateFormFile("Image", "fw.bin")
+	fw.Write(image)
+	mw.Close()
+
+	w := serveStream(s, "/firmware/Image", mw.FormDataContentType(), body.Bytes(), http.Header{xChecksum: {checksum(body.Bytes())}})
+	want := UploadReply{Size: int64(len(image)), Sum: checksum(image), Name: "2.0/fw.bin"}
+	if got := decodeUploadReply(t, w); got != want {
+		t.Fatalf("reply: got %+v, want %+v", got, want)
+	}
+
+	w = serveStream(s, "/firmware/Image", mw.FormDataContentType(), body.Bytes(), http.Header{xChecksum: {checksum(image)}})
+	if got, want := decodeErrCode(t, w), codes.ChecksumFail; got != want {
+		t.Fatalf("code: got %v, want %v", got, want)
+	}
+}
+
+func TestStreamDownload(t *testing.T) {
+	s := NewServer(nil)
+	if err := s.Register("/firmware", Firmware{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.SetCoalesce("/firmware/Download"); err == nil {
+		t.Fatalf("SetCoalesce: return nil error for stream method")
+	}
+
+	// 解码器不会读到请求体结尾, 校验和仍需比较
+	r := httptest.NewRequest("POST", "/firmware/Download", io.MultiReader(strings.NewReader(`"v1"`), strings.NewReader("\n")))
+	r.ContentLength = -1
+	r.Header.Set(xChecksum, checksum([]byte("other")))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if got, want := decodeErrCode(t, w), codes.ChecksumFail; got != want {
+		t.Fatalf("checksum: code: got %v, want %v", got, want)
+	}
+
+	body := []byte(`"v1"` + "\n")
+	w = serveStream(s, "/firmware/Download", "", body, http.Header{xChecksum: {checksum(body)}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: %d, body: %s", w.Code, w.Body)
+	}
+	h := w.Header()
+	for key, want := range map[string]string{
+		"Content-Type":        "application/x-binary",
+		"Content-Length":      "8",
+		"Content-Disposition": "attachment; filename=v1.bin",
+	} {
+		if got := h.Get(key); got != want {
+			t.Fatalf("%s: got %q, want %q", key, got, want)
+		}
+	}
+	if got, want := w.Body.String(), "image:v1"; got != want {
+		t.Fatalf("body: got %q, want %q", got, want)
+	}
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/firmware/Logs", "", nil)
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if got, want := string(b), "line1\nline2\n"; got != want {
+		t.Fatalf("body: got %q, want %q", got, want)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "application/octet-stream"; got != want {
+		t.Fatalf("Content-Type: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ironzhang/pearls/uuid"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
+)
+
+// startServerSpan 根据traceparent和X-Trace-Id请求头创建server span.
+// 没有X-Trace-Id时使用traceparent中的trace id, 没有traceparent时由X-Trace-Id导出trace id.
+func startServerSpan(r *http.Request) (string, *tracing.Span) {
+	parent, ok := tracing.Extract(r.Header)
+	traceID := r.Header.Get(xTraceID)
+	if traceID == "" {
+		if ok {
+			traceID = parent.TraceID.String()
+		} else {
+			traceID = uuid.New().String()
+		}
+	}
+	if !ok {
+		parent = tracing.SpanContext{TraceID: tracing.TraceIDFromString(traceID)}
+	}
+	return traceID, tracing.Default.StartSpan(r.URL.Path, tracing.KindServer, parent)
+}
+
+// startClientSpan 创建client span并写入traceparent请求头, 调用前X-Trace-Id请求头需已设置.
+func startClientSpan(ctx context.Context, path string, h http.Header) *tracing.Span {
+	var parent tracing.SpanContext
+	if s := tracing.SpanFromContext(ctx); s != nil {
+		parent = s.Context()
+	} else {
+		parent.TraceID = tracing.TraceIDFromString(h.Get(xTraceID))
+	}
+	span := tracing.Default.StartSpan(path, tracing.KindClient, parent)
+	tracing.Inject(h, span.Context())
+	return span
+}
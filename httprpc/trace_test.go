@@ -0,0 +1,90 @@
+package httprpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*tracing.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(d *tracing.SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, d)
+	r.mu.Unlock()
+}
+
+type Proxy struct {
+	client *Client
+}
+
+func (p *Proxy) Add(ctx context.Context, args Args, reply *Reply) error {
+	return p.client.Call(ctx, "/arith/Add", args, reply)
+}
+
+func TestTracePropagation(t *testing.T) {
+	var rec spanRecorder
+	tracing.Default.SetExporter(&rec)
+	defer tracing.Default.SetExporter(nil)
+
+	var a Arith
+	backend := NewServer(nil)
+	if err := backend.Register("/arith", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	bsvr := httptest.NewServer(backend)
+	defer bsvr.Close()
+
+	frontend := NewServer(nil)
+	if err := frontend.Register("/proxy", &Proxy{client: NewClient(bsvr.URL, nil)}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	fsvr := httptest.NewServer(frontend)
+	defer fsvr.Close()
+
+	ctx := &Context{Context: context.Background(), TraceID: "4bf92f35-77b3-4da6-a3ce-929d0e0e4736"}
+	var reply Reply
+	if err := NewClient(fsvr.URL, nil).Call(ctx, "/proxy/Add", Args{A: 1, B: 2}, &reply); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+
+	// server span在响应写出后结束, 等待其输出
+	for i := 0; i < 100; i++ {
+		rec.mu.Lock()
+		n := len(rec.spans)
+		rec.mu.Unlock()
+		if n >= 4 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if got, want := len(rec.spans), 4; got != want {
+		t.Fatalf("spans: got %v, want %v", got, want)
+	}
+	byName := make(map[string]*tracing.SpanData)
+	for _, s := range rec.spans {
+		if s.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: trace id: %s", s.Name, s.TraceID)
+		}
+		byName[string(s.Kind)+" "+s.Name] = s
+	}
+	chain := []string{"client /proxy/Add", "server /proxy/Add", "client /arith/Add", "server /arith/Add"}
+	for i := 1; i < len(chain); i++ {
+		parent, child := byName[chain[i-1]], byName[chain[i]]
+		if parent == nil || child == nil {
+			t.Fatalf("span %s or %s not found", chain[i-1], chain[i])
+		}
+		if child.ParentID != parent.SpanID {
+			t.Errorf("%s parent: got %v, want %v", chain[i], child.ParentID, parent.SpanID)
+		}
+	}
+}
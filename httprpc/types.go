@@ -1,8 +1,11 @@
 package httprpc
 
+import "encoding/json"
+
 type errReply struct {
-	Code  int
-	Error string
-	Cause string
-	Stack string `json:",omitempty"`
+	Code    int
+	Error   string
+	Cause   string
+	Stack   string          `json:",omitempty"`
+	Details json.RawMessage `json:",omitempty"`
 }
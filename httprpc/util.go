@@ -2,14 +2,15 @@ package httprpc
 
 import (
 	"net/http"
-	"runtime"
+	gopath "path"
 	"strings"
 
 	"github.com/ironzhang/pearls/uuid"
 )
 
 const (
-	xTraceID = "X-Trace-Id"
+	xTraceID  = "X-Trace-Id"
+	xClientID = "X-Client-Id"
 )
 
 func normalizePath(path string) string {
@@ -33,11 +34,36 @@ func splitPath(path string) (string, string) {
 	return path[:i], path[i+1:]
 }
 
-func runtimeStack() []byte {
-	const size = 64 << 10
-	buf := make([]byte, size)
-	buf = buf[:runtime.Stack(buf, false)]
-	return buf
+// MatchPath 判断path是否匹配pattern, pattern使用path.Match语法,
+// 以"/**"结尾时匹配该前缀本身及其下的所有路径.
+func MatchPath(pattern, path string) bool {
+	pattern, path = normalizePath(pattern), normalizePath(path)
+	if strings.HasSuffix(pattern, "/**") {
+		prefix := strings.TrimSuffix(pattern, "/**")
+		if prefix == "" {
+			return true
+		}
+		if ok, _ := gopath.Match(prefix, path); ok {
+			return true
+		}
+		for i := len(path) - 1; i > 0; i-- {
+			if path[i] == '/' {
+				if ok, _ := gopath.Match(prefix, path[:i]); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	ok, _ := gopath.Match(pattern, path)
+	return ok
+}
+
+func joinPath(class, method string) string {
+	if class == "/" {
+		return class + method
+	}
+	return class + "/" + method
 }
 
 func getHeaderContentType(h http.Header) string {
@@ -48,22 +74,9 @@ func setHeaderContentType(h http.Header, contentType string) {
 	h.Set("Content-Type", contentType)
 }
 
-func getHeaderTraceID(h http.Header) string {
-	if traceID := h.Get(xTraceID); traceID != "" {
-		return traceID
-	}
-	return uuid.New().String()
-}
-
 func setHeaderTraceID(h http.Header, traceID string) {
 	if traceID == "" {
 		traceID = uuid.New().String()
 	}
 	h.Set(xTraceID, traceID)
 }
-
-func setCors(h http.Header, origin string) {
-	h.Set("Access-Control-Allow-Origin", origin)
-	h.Set("Access-Control-Allow-Methods", "*")
-	h.Set("Access-Control-Allow-Headers", "*")
-}
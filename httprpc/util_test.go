@@ -49,3 +49,26 @@ func TestSplitPath(t *testing.T) {
 		t.Logf("splitPath(%s): class got %v, method got %v", tt.path, class, method)
 	}
 }
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{pattern: "/arith/Add", path: "/arith/Add", match: true},
+		{pattern: "/arith/*", path: "/arith/Add", match: true},
+		{pattern: "/arith/*", path: "/arith/v0/Add", match: false},
+		{pattern: "/arith/**", path: "/arith/v0/Add", match: true},
+		{pattern: "/arith/**", path: "/arith", match: true},
+		{pattern: "/arith/**", path: "/arithmetic/Add", match: false},
+		{pattern: "/*/v1/**", path: "/arith/v1/Add", match: true},
+		{pattern: "/**", path: "/Println", match: true},
+		{pattern: "arith/Add/", path: "/arith/Add", match: true},
+	}
+	for _, tt := range tests {
+		if got, want := MatchPath(tt.pattern, tt.path), tt.match; got != want {
+			t.Errorf("MatchPath(%s, %s): got %v, want %v", tt.pattern, tt.path, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package httprpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	headerDeprecation = "Deprecation"
+	headerSunset      = "Sunset"
+	headerLink        = "Link"
+)
+
+// Alias 将alias注册为target的别名.
+//
+// target为已注册的类时, alias下的方法与target下的同名方法相同;
+// target为已注册的方法时, alias为单个方法的别名.
+func (s *Server) Alias(alias, target string) error {
+	alias, target = normalizePath(alias), s.resolvePath(normalizePath(target))
+	if _, ok := s.classes.Load(alias); ok {
+		return fmt.Errorf("alias: class already defined: %s", alias)
+	}
+	if _, ok := s.classes.Load(target); !ok {
+		if _, _, err := s.lookupByPath(target); err != nil {
+			return fmt.Errorf("alias: target %s: %v", target, err)
+		}
+		if _, _, err := s.lookupMethod(splitPath(alias)); err == nil {
+			return fmt.Errorf("alias: method already defined: %s", alias)
+		}
+	}
+	if _, loaded := s.aliases.LoadOrStore(alias, target); loaded {
+		return fmt.Errorf("alias: alias already defined: %s", alias)
+	}
+	return nil
+}
+
+// ResolvePath 返回路径解析别名后的方法路径, 不是别名时返回规范化的path.
+// 中间件应按该路径做访问控制, 否则别名可以绕过针对实际路径的规则.
+func (s *Server) ResolvePath(path string) string {
+	return s.resolvePath(normalizePath(path))
+}
+
+// resolvePath 将别名路径解析为实际注册的方法路径
+func (s *Server) resolvePath(path string) string {
+	if v, ok := s.aliases.Load(path); ok {
+		return v.(string)
+	}
+	className, methodName := splitPath(path)
+	if v, ok := s.aliases.Load(className); ok {
+		return joinPath(v.(string), methodName)
+	}
+	return path
+}
+
+// deprecationClients 每个废弃路径记录的client id上限, 达到上限时清空重新记录,
+// client id由调用方提供, 不能无限增长.
+const deprecationClients = 1024
+
+type deprecation struct {
+	sunset time.Time
+	link   string
+
+	mu      sync.Mutex
+	clients map[string]struct{}
+}
+
+// firstCall 返回clientID是否第一次调用该路径
+func (d *deprecation) firstCall(clientID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.clients[clientID]; ok {
+		return false
+	}
+	if d.clients == nil || len(d.clients) >= deprecationClients {
+		d.clients = make(map[string]struct{})
+	}
+	d.clients[clientID] = struct{}{}
+	return true
+}
+
+// Deprecate 将类或方法路径标记为已废弃, 通过该路径的调用在响应中携带
+// Deprecation/Sunset头, 并按client id记录一次警告日志. sunset为零值时不设置Sunset头.
+func (s *Server) Deprecate(path string, sunset time.Time, link string) error {
+	path = normalizePath(path)
+	if _, ok := s.classes.Load(path); !ok {
+		if _, ok = s.aliases.Load(path); !ok {
+			if _, _, err := s.lookupByPath(path); err != nil {
+				return fmt.Errorf("deprecate: %v", err)
+			}
+		}
+	}
+	s.deprecations.Store(path, &deprecation{sunset: sunset, link: link})
+	return nil
+}
+
+func (s *Server) lookupDeprecation(path string) (string, *deprecation) {
+	if v, ok := s.deprecations.Load(path); ok {
+		return path, v.(*deprecation)
+	}
+	className, _ := splitPath(path)
+	if v, ok := s.deprecations.Load(className); ok {
+		return className, v.(*deprecation)
+	}
+	return "", nil
+}
+
+func (s *Server) checkDeprecation(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	path := normalizePath(r.URL.Path)
+	dpath, d := s.lookupDeprecation(path)
+	if d == nil {
+		return
+	}
+
+	w.Header().Set(headerDeprecation, "true")
+	if !d.sunset.IsZero() {
+		w.Header().Set(headerSunset, d.sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.link != "" {
+		w.Header().Add(headerLink, fmt.Sprintf("<%s>; rel=\"deprecation\"", d.link))
+	}
+
+	clientID := r.Header.Get(xClientID)
+	if d.firstCall(clientID) {
+		Logger().WithContext(ctx).Warnw("deprecated path called", "path", path,
+			"deprecated", dpath, "clientId", clientID, "sunset", d.sunset)
+	}
+}
+
+// checkDeprecation client使用, 每个路径只输出一次警告
+func (c *Client) checkDeprecation(ctx context.Context, path string, h http.Header) {
+	if h.Get(headerDeprecation) == "" {
+		return
+	}
+	if _, loaded := c.deprecated.LoadOrStore(path, struct{}{}); loaded {
+		return
+	}
+	Logger().WithContext(ctx).Warnw("calling deprecated path", "url", c.url, "path", path,
+		"sunset", h.Get(headerSunset), "link", h.Get(headerLink))
+}
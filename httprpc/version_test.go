@@ -0,0 +1,150 @@
+package httprpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestServerAlias(t *testing.T) {
+	var a Arith
+	s := NewServer(nil)
+	if err := s.Register("/arith/v0", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Alias("/arith/v1", "/arith/v0"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+	if err := s.Alias("/arith/v1/Sum", "/arith/v0/Add"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+	if err := s.Alias("/arith/v1/Add", "/arith/v0/Mul"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+
+	errors := []struct {
+		alias  string
+		target string
+	}{
+		{alias: "/arith/v0", target: "/arith/v1"},
+		{alias: "/arith/v1", target: "/arith/v0"},
+		{alias: "/arith/v0/Add", target: "/arith/v0/Mul"},
+		{alias: "/arith/v2", target: "/arith/none"},
+	}
+	for _, tt := range errors {
+		if err := s.Alias(tt.alias, tt.target); err == nil {
+			t.Errorf("Alias(%s, %s) return error is nil", tt.alias, tt.target)
+		} else {
+			t.Logf("Alias(%s, %s): %v", tt.alias, tt.target, err)
+		}
+	}
+	if err := s.Register("/arith/v1", &a); err == nil {
+		t.Errorf("Register return error is nil")
+	}
+
+	tests := []struct {
+		path  string
+		reply Reply
+	}{
+		{path: "/arith/v0/Add", reply: Reply{C: 5}},
+		{path: "/arith/v1/Add", reply: Reply{C: 6}},
+		{path: "/arith/v1/Div", reply: Reply{C: 1}},
+		{path: "/arith/v1/Sum", reply: Reply{C: 5}},
+	}
+	for _, tt := range tests {
+		var reply Reply
+		if err := callTestServer(s, tt.path, Args{A: 3, B: 2}, &reply); err != nil {
+			t.Fatalf("callTestServer(%s): %v", tt.path, err)
+		}
+		if got, want := reply, tt.reply; got != want {
+			t.Errorf("callTestServer(%s): reply: got %v, want %v", tt.path, got, want)
+		}
+	}
+
+	aliases := make(map[string]string)
+	for _, m := range s.Methods() {
+		if m.Alias != "" {
+			aliases[m.Path] = m.Alias
+		}
+	}
+	if got, want := aliases["/arith/v1/Add"], "/arith/v0/Mul"; got != want {
+		t.Errorf("/arith/v1/Add alias: got %v, want %v", got, want)
+	}
+	if got, want := aliases["/arith/v1/Div"], "/arith/v0/Div"; got != want {
+		t.Errorf("/arith/v1/Div alias: got %v, want %v", got, want)
+	}
+}
+
+func TestServerDeprecate(t *testing.T) {
+	var a Arith
+	s := NewServer(nil)
+	if err := s.Register("/arith/v0", &a); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Alias("/arith/v1", "/arith/v0"); err != nil {
+		t.Fatalf("Alias: %v", err)
+	}
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Deprecate("/arith/v0", sunset, "https://example.com/arith/v1"); err != nil {
+		t.Fatalf("Deprecate: %v", err)
+	}
+	if err := s.Deprecate("/arith/v1/Div", time.Time{}, ""); err != nil {
+		t.Fatalf("Deprecate: %v", err)
+	}
+	if err := s.Deprecate("/none", sunset, ""); err == nil {
+		t.Fatalf("Deprecate return error is nil")
+	}
+
+	tests := []struct {
+		path        string
+		deprecation string
+		sunset      string
+	}{
+		{path: "/arith/v0/Add", deprecation: "true", sunset: "Tue, 01 Jan 2030 00:00:00 GMT"},
+		{path: "/arith/v1/Add", deprecation: "", sunset: ""},
+		{path: "/arith/v1/Div", deprecation: "true", sunset: ""},
+	}
+	for _, tt := range tests {
+		w, _ := serveTestHTTP(s, "POST", tt.path, []byte(`{"A":1,"B":1}`))
+		if got, want := w.Header().Get("Deprecation"), tt.deprecation; got != want {
+			t.Errorf("%s: Deprecation: got %q, want %q", tt.path, got, want)
+		}
+		if got, want := w.Header().Get("Sunset"), tt.sunset; got != want {
+			t.Errorf("%s: Sunset: got %q, want %q", tt.path, got, want)
+		}
+	}
+
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+	c := NewClient(svr.URL, nil)
+	for i := 0; i < 2; i++ {
+		var reply Reply
+		if err := c.Call(context.Background(), "/arith/v0/Add", Args{A: 1, B: 1}, &reply); err != nil {
+			t.Fatalf("Call: %v", err)
+		}
+	}
+	if _, ok := c.deprecated.Load("/arith/v0/Add"); !ok {
+		t.Errorf("client deprecation warning not recorded")
+	}
+	var h http.Header
+	c.checkDeprecation(context.Background(), "/arith/v1/Add", h)
+	if _, ok := c.deprecated.Load("/arith/v1/Add"); ok {
+		t.Errorf("client deprecation warning recorded without header")
+	}
+}
+
+func TestDeprecationClients(t *testing.T) {
+	var d deprecation
+	if !d.firstCall("c1") || d.firstCall("c1") {
+		t.Fatalf("firstCall: c1 should be first only once")
+	}
+	for i := 0; i < 3*deprecationClients; i++ {
+		d.firstCall(strconv.Itoa(i))
+	}
+	if got := len(d.clients); got > deprecationClients {
+		t.Fatalf("clients: got %d, want <= %d", got, deprecationClients)
+	}
+}
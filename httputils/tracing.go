@@ -0,0 +1,54 @@
+package httputils
+
+import (
+	"net/http"
+
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
+)
+
+// NewTracingRoundTripper 返回为每个请求创建client span的RoundTripper.
+// 请求的context中有span时作为父span, 否则由X-Trace-Id导出trace id.
+func NewTracingRoundTripper(tracer *tracing.Tracer, transport http.RoundTripper) *TracingRoundTripper {
+	if tracer == nil {
+		tracer = tracing.Default
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &TracingRoundTripper{
+		tracer:    tracer,
+		transport: transport,
+	}
+}
+
+type TracingRoundTripper struct {
+	tracer    *tracing.Tracer
+	transport http.RoundTripper
+}
+
+func (rt *TracingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	var parent tracing.SpanContext
+	if s := tracing.SpanFromContext(r.Context()); s != nil {
+		parent = s.Context()
+	} else {
+		parent.TraceID = tracing.TraceIDFromString(r.Header.Get(X_TRACE_ID))
+	}
+	span := rt.tracer.StartSpan(r.URL.Path, tracing.KindClient, parent)
+	defer span.Finish()
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.url", r.URL.String())
+
+	// RoundTripper不应修改原请求
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = r.Header.Clone()
+	tracing.Inject(r2.Header, span.Context())
+
+	resp, err := rt.transport.RoundTrip(r2)
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+		return resp, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	return resp, err
+}
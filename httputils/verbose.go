@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"git.ablecloud.cn/ablecloud/ac-comm-lib/tracing"
 	"git.ablecloud.cn/ablecloud/ac-comm-lib/zaplog"
 	"github.com/ironzhang/pearls/uuid"
 )
@@ -43,7 +44,11 @@ func (v *Verbose) enabled(verbose bool) bool {
 func parseTraceId(h http.Header) string {
 	traceId := h.Get(X_TRACE_ID)
 	if traceId == "" {
-		traceId = uuid.New().String()
+		if sc, ok := tracing.Extract(h); ok {
+			traceId = sc.TraceID.String()
+		} else {
+			traceId = uuid.New().String()
+		}
 		h.Set(X_TRACE_ID, traceId)
 	}
 	return traceId
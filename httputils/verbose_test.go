@@ -3,6 +3,7 @@ package httputils
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -70,6 +71,19 @@ func TestParseTraceId(t *testing.T) {
 			t.Logf("%s got(%v) == want(%v)", casename, got, want)
 		}
 	}
+
+	{
+		casename := "SetTraceparent"
+
+		h := make(http.Header)
+		h.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		got, want := parseTraceId(h), "4bf92f3577b34da6a3ce929d0e0e4736"
+		if got != want || h.Get(X_TRACE_ID) != want {
+			t.Errorf("%s got(%v) != want(%v)", casename, got, want)
+		} else {
+			t.Logf("%s got(%v) == want(%v)", casename, got, want)
+		}
+	}
 }
 
 func TestVerboseHandler(t *testing.T) {
@@ -99,3 +113,27 @@ func TestVerboseRoundTripper(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTracingRoundTripper(t *testing.T) {
+	var traceparent string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer s.Close()
+
+	rt := NewTracingRoundTripper(nil, nil)
+	r, err := http.NewRequest("GET", s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set(X_TRACE_ID, "4bf92f35-77b3-4da6-a3ce-929d0e0e4736")
+	if _, err = rt.RoundTrip(r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(traceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("traceparent: %q", traceparent)
+	}
+	if r.Header.Get("Traceparent") != "" {
+		t.Errorf("origin request modified")
+	}
+}
@@ -0,0 +1,204 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type TraceID [16]byte
+
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+type SpanID [8]byte
+
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func newTraceID() (id TraceID) {
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// TraceIDFromString 将X-Trace-Id转换为TraceID.
+// 32位十六进制字符串(包括UUID格式)直接解码, 其它字符串取哈希.
+func TraceIDFromString(s string) (id TraceID) {
+	if s == "" {
+		return id
+	}
+	if b, err := hex.DecodeString(strings.Replace(s, "-", "", -1)); err == nil && len(b) == len(id) {
+		copy(id[:], b)
+		if id.IsValid() {
+			return id
+		}
+	}
+	h := fnv.New128a()
+	h.Write([]byte(s))
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+const FlagSampled = 0x01
+
+// SpanContext 跨进程传递的span信息, 对应W3C traceparent/tracestate.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent 返回W3C traceparent格式: version-traceid-spanid-flags
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+func ParseTraceparent(s string) (sc SpanContext, err error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return sc, errInvalidTraceparent
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, errInvalidTraceparent
+	}
+	if _, err = hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, errInvalidTraceparent
+	}
+	if _, err = hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, errInvalidTraceparent
+	}
+	var flags [1]byte
+	if _, err = hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, errInvalidTraceparent
+	}
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, errInvalidTraceparent
+	}
+	return sc, nil
+}
+
+type Kind string
+
+const (
+	KindInternal Kind = "internal"
+	KindServer   Kind = "server"
+	KindClient   Kind = "client"
+)
+
+// SpanData 已结束的span, 交给Exporter输出.
+type SpanData struct {
+	TraceID    string
+	SpanID     string
+	ParentID   string `json:",omitempty"`
+	Name       string
+	Kind       Kind
+	Start      time.Time
+	End        time.Time
+	Duration   time.Duration
+	Attributes map[string]interface{} `json:",omitempty"`
+}
+
+type Span struct {
+	tracer  *Tracer
+	name    string
+	kind    Kind
+	context SpanContext
+	parent  SpanID
+	start   time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes map[string]interface{}
+}
+
+func (s *Span) Context() SpanContext {
+	return s.context
+}
+
+func (s *Span) TraceID() TraceID {
+	return s.context.TraceID
+}
+
+func (s *Span) SpanID() SpanID {
+	return s.context.SpanID
+}
+
+func (s *Span) ParentID() SpanID {
+	return s.parent
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+// Finish 结束span并输出到Tracer的Exporter, 重复调用无效.
+func (s *Span) Finish() {
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end = time.Now()
+	d := &SpanData{
+		TraceID:    s.context.TraceID.String(),
+		SpanID:     s.context.SpanID.String(),
+		Name:       s.name,
+		Kind:       s.kind,
+		Start:      s.start,
+		End:        s.end,
+		Duration:   s.end.Sub(s.start),
+		Attributes: s.attributes,
+	}
+	if s.parent.IsValid() {
+		d.ParentID = s.parent.String()
+	}
+	s.mu.Unlock()
+	s.tracer.export(d)
+}
+
+type spanKey struct{}
+
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderTraceparent = "Traceparent"
+	HeaderTracestate  = "Tracestate"
+)
+
+// Exporter 输出已结束的span
+type Exporter interface {
+	ExportSpan(d *SpanData)
+}
+
+type Tracer struct {
+	mu       sync.RWMutex
+	exporter Exporter
+}
+
+func NewTracer(e Exporter) *Tracer {
+	return &Tracer{exporter: e}
+}
+
+// Default 默认Tracer, 未设置Exporter时span只用于传递trace/span id.
+var Default = NewTracer(nil)
+
+func (t *Tracer) SetExporter(e Exporter) {
+	t.mu.Lock()
+	t.exporter = e
+	t.mu.Unlock()
+}
+
+func (t *Tracer) export(d *SpanData) {
+	t.mu.RLock()
+	e := t.exporter
+	t.mu.RUnlock()
+	if e != nil {
+		e.ExportSpan(d)
+	}
+}
+
+// StartSpan 创建span. parent有效时作为其子span; 只有TraceID有效时沿用该TraceID创建根span;
+// 否则创建新的trace.
+func (t *Tracer) StartSpan(name string, kind Kind, parent SpanContext) *Span {
+	s := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+	}
+	s.context = SpanContext{
+		TraceID:    parent.TraceID,
+		SpanID:     newSpanID(),
+		Flags:      parent.Flags,
+		TraceState: parent.TraceState,
+	}
+	if parent.IsValid() {
+		s.parent = parent.SpanID
+	} else {
+		s.context.Flags |= FlagSampled
+	}
+	if !s.context.TraceID.IsValid() {
+		s.context.TraceID = newTraceID()
+	}
+	return s
+}
+
+// Extract 从请求头中读取traceparent/tracestate
+func Extract(h http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceparent(h.Get(HeaderTraceparent))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = h.Get(HeaderTracestate)
+	return sc, true
+}
+
+// Inject 将sc写入traceparent/tracestate请求头
+func Inject(h http.Header, sc SpanContext) {
+	h.Set(HeaderTraceparent, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(HeaderTracestate, sc.TraceState)
+	} else {
+		h.Del(HeaderTracestate)
+	}
+}
+
+// JSONExporter 将span以JSON行的形式写入w
+type JSONExporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenFileExporter 以追加方式打开文件作为JSONExporter的输出
+func OpenFileExporter(filename string) (*JSONExporter, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONExporter(f), nil
+}
+
+func (e *JSONExporter) ExportSpan(d *SpanData) {
+	e.mu.Lock()
+	e.enc.Encode(d)
+	e.mu.Unlock()
+}
+
+func (e *JSONExporter) Close() error {
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
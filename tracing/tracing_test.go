@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		s  string
+		ok bool
+	}{
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: true},
+		{s: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", ok: true},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", ok: false},
+		{s: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: false},
+		{s: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", ok: false},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ok: false},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01", ok: false},
+		{s: "00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01", ok: false},
+		{s: "", ok: false},
+	}
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.s)
+		if got, want := err == nil, tt.ok; got != want {
+			t.Errorf("ParseTraceparent(%q): got %v, want %v", tt.s, got, want)
+			continue
+		}
+		if tt.ok && tt.s[:2] == "00" && sc.Traceparent() != tt.s {
+			t.Errorf("Traceparent: got %q, want %q", sc.Traceparent(), tt.s)
+		}
+	}
+}
+
+func TestTraceIDFromString(t *testing.T) {
+	if got, want := TraceIDFromString("4bf92f35-77b3-4da6-a3ce-929d0e0e4736").String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("uuid: got %v, want %v", got, want)
+	}
+	a, b := TraceIDFromString("trace-1"), TraceIDFromString("trace-1")
+	if !a.IsValid() || a != b {
+		t.Errorf("hash: %v, %v", a, b)
+	}
+	if TraceIDFromString("").IsValid() {
+		t.Errorf("empty string returns valid trace id")
+	}
+}
+
+func TestSpan(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewJSONExporter(&buf))
+
+	root := tracer.StartSpan("root", KindServer, SpanContext{})
+	if !root.Context().IsValid() || root.ParentID().IsValid() {
+		t.Fatalf("root span: %+v", root.Context())
+	}
+	ctx := ContextWithSpan(context.Background(), root)
+	child := tracer.StartSpan("child", KindClient, SpanFromContext(ctx).Context())
+	child.SetAttribute("k", "v")
+	if child.TraceID() != root.TraceID() || child.ParentID() != root.SpanID() {
+		t.Fatalf("child span: %+v, parent: %v", child.Context(), child.ParentID())
+	}
+
+	h := make(http.Header)
+	Inject(h, child.Context())
+	sc, ok := Extract(h)
+	if !ok || sc.SpanID != child.SpanID() {
+		t.Fatalf("Extract: %+v, %v", sc, ok)
+	}
+
+	child.Finish()
+	child.Finish()
+	root.Finish()
+
+	dec := json.NewDecoder(&buf)
+	var spans []SpanData
+	for dec.More() {
+		var d SpanData
+		if err := dec.Decode(&d); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		spans = append(spans, d)
+	}
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("spans: got %v, want %v", got, want)
+	}
+	if spans[0].ParentID != spans[1].SpanID || spans[0].Attributes["k"] != "v" {
+		t.Errorf("spans: %+v", spans)
+	}
+}
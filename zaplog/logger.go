@@ -2,6 +2,7 @@ package zaplog
 
 import (
 	"context"
+	"sync"
 
 	"go.uber.org/zap"
 )
@@ -10,16 +11,26 @@ type TraceIDContext interface {
 	GetTraceID() string
 }
 
+type SpanIDContext interface {
+	GetSpanID() string
+}
+
 type values struct {
 	traceID  string
+	spanID   string
 	errorStr string
 	function string
 }
 
+// Logger 创建后不再修改, Base和Sugar的结果在首次使用时生成并缓存.
 type Logger struct {
 	base   *zap.Logger
 	values values
 	fields []zap.Field
+
+	once  sync.Once
+	zap   *zap.Logger
+	sugar *zap.SugaredLogger
 }
 
 func NewLogger(base *zap.Logger) *Logger {
@@ -56,6 +67,14 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 			log.values.traceID = id
 		}
 	}
+	if sc, ok := ctx.(SpanIDContext); ok {
+		if id := sc.GetSpanID(); id != "" {
+			if log == l {
+				log = log.clone(0)
+			}
+			log.values.spanID = id
+		}
+	}
 	return log
 }
 
@@ -69,10 +88,23 @@ func (l *Logger) WithFunction(name string) *Logger {
 }
 
 func (l *Logger) Base() *zap.Logger {
+	l.once.Do(l.build)
+	return l.zap
+}
+
+func (l *Logger) build() {
+	l.zap = l.newBase()
+	l.sugar = l.zap.Sugar()
+}
+
+func (l *Logger) newBase() *zap.Logger {
 	log := l.base
 	if l.values.traceID != "" {
 		log = log.With(zap.String("TraceID", l.values.traceID))
 	}
+	if l.values.spanID != "" {
+		log = log.With(zap.String("SpanID", l.values.spanID))
+	}
 	if l.values.errorStr != "" {
 		log = log.With(zap.String("Error", l.values.errorStr))
 	}
@@ -86,7 +118,8 @@ func (l *Logger) Base() *zap.Logger {
 }
 
 func (l *Logger) Sugar() *zap.SugaredLogger {
-	return l.Base().Sugar()
+	l.once.Do(l.build)
+	return l.sugar
 }
 
 func (l *Logger) Debug(msg string, fields ...zap.Field) {
@@ -16,6 +16,15 @@ func (c *TContext) GetTraceID() string {
 	return c.id
 }
 
+type TSpanContext struct {
+	TContext
+	span string
+}
+
+func (c *TSpanContext) GetSpanID() string {
+	return c.span
+}
+
 func NewTestLogger(t *testing.T) *Logger {
 	l, err := zap.NewDevelopment()
 	if err != nil {
@@ -33,4 +42,29 @@ func TestLogger(t *testing.T) {
 	logger.WithContext(&TContext{id: "2"}).Debugw("debugw", "A", "a", "B", 1)
 	logger.WithContext(&TContext{id: "3"}).Debugf("debugf A=%s, B=%d", "a", 1)
 	logger.WithContext(NewContext()).Debugf("context")
+	logger.WithContext(&TSpanContext{TContext: TContext{id: "4"}, span: "00f067aa0ba902b7"}).Debugf("span")
+}
+
+func TestLoggerWithSpanContext(t *testing.T) {
+	logger := NewTestLogger(t)
+	log := logger.WithContext(&TSpanContext{TContext: TContext{id: "1"}, span: "2"})
+	if got, want := log.values.traceID, "1"; got != want {
+		t.Errorf("traceID: got %v, want %v", got, want)
+	}
+	if got, want := log.values.spanID, "2"; got != want {
+		t.Errorf("spanID: got %v, want %v", got, want)
+	}
+	if logger.values.spanID != "" {
+		t.Errorf("origin logger modified")
+	}
+}
+
+func TestLoggerBaseCached(t *testing.T) {
+	log := NewTestLogger(t).WithFunction("TestLoggerBaseCached")
+	if log.Base() != log.Base() {
+		t.Errorf("Base is rebuilt on each call")
+	}
+	if log.Sugar() != log.Sugar() {
+		t.Errorf("Sugar is rebuilt on each call")
+	}
 }